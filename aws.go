@@ -0,0 +1,220 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+)
+
+// ssmBatchSize is the maximum number of parameters GetParameters accepts
+const ssmBatchSize = 10
+
+// SSMProvider resolves references against the AWS SSM parameter store.
+// A reference is the parameter name or ARN, optionally followed by a
+// ":version" or ":label" selector, e.g. "/service/db:3" or "/service/db:prod",
+// or the equivalent "?version=3" or "?label=prod" query. A name ending with "/", e.g. "/service/prod/",
+// fills a struct or map field with all the parameters under it.
+// StringList parameters are returned comma separated, so they can be parsed into slice fields
+type SSMProvider struct {
+	Session *session.Session
+}
+
+// Resolve gets the value of the parameter with the given name from the AWS SSM parameter store
+func (s *SSMProvider) Resolve(key string) (string, error) {
+	return s.ResolveContext(context.Background(), key)
+}
+
+// ResolveContext is Resolve with a context for the request
+func (s *SSMProvider) ResolveContext(ctx context.Context, key string) (string, error) {
+	if s.Session == nil {
+		return key, fmt.Errorf("aws connection is not set")
+	}
+
+	name, err := ssmParameterName(key)
+	if err != nil {
+		return "", err
+	}
+	input := &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	}
+
+	output, err := ssm.New(s.Session).GetParameterWithContext(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("err while get aws secret: %w", err)
+	}
+	if output == nil || output.Parameter == nil || output.Parameter.Value == nil {
+		return "", fmt.Errorf("aws parameter %q has no value", key)
+	}
+
+	return *output.Parameter.Value, nil
+}
+
+// ResolveBatch gets the parameters with GetParameters, ten at a time
+func (s *SSMProvider) ResolveBatch(ctx context.Context, keys []string) (map[string]string, error) {
+	if s.Session == nil {
+		return nil, fmt.Errorf("aws connection is not set")
+	}
+
+	names := map[string]string{}
+	for _, key := range keys {
+		name, err := ssmParameterName(key)
+		if err != nil {
+			continue
+		}
+		names[name] = key
+	}
+	batch := make([]*string, 0, ssmBatchSize)
+	for name := range names {
+		batch = append(batch, aws.String(name))
+	}
+	sort.Slice(batch, func(i, j int) bool { return *batch[i] < *batch[j] })
+
+	client := ssm.New(s.Session)
+	values := make(map[string]string, len(names))
+	for start := 0; start < len(batch); start += ssmBatchSize {
+		end := start + ssmBatchSize
+		if end > len(batch) {
+			end = len(batch)
+		}
+		output, err := client.GetParametersWithContext(ctx, &ssm.GetParametersInput{
+			Names:          batch[start:end],
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("err while get aws secrets: %w", err)
+		}
+		for _, param := range output.Parameters {
+			if param.Value == nil {
+				continue
+			}
+			name := aws.StringValue(param.Name) + aws.StringValue(param.Selector)
+			if key, ok := names[name]; ok {
+				values[key] = *param.Value
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// ResolvePath gets all the parameters under path with GetParametersByPath, recursively.
+// The parameters are returned by their names relative to path with "/" replaced by "."
+func (s *SSMProvider) ResolvePath(ctx context.Context, path string) (map[string]string, error) {
+	if s.Session == nil {
+		return nil, fmt.Errorf("aws connection is not set")
+	}
+	if strings.Contains(path, "?") {
+		return nil, fmt.Errorf("aws parameter path %q does not accept selectors", path)
+	}
+
+	values := map[string]string{}
+	input := &ssm.GetParametersByPathInput{
+		Path:           aws.String(path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	}
+	err := ssm.New(s.Session).GetParametersByPathPagesWithContext(ctx, input, func(output *ssm.GetParametersByPathOutput, _ bool) bool {
+		for _, param := range output.Parameters {
+			name := strings.TrimPrefix(aws.StringValue(param.Name), path)
+			values[strings.ReplaceAll(name, "/", ".")] = aws.StringValue(param.Value)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("err while get aws secrets by path: %w", err)
+	}
+
+	return values, nil
+}
+
+// ssmParameterName returns the parameter name for key, turning
+// a "?version=" or "?label=" selector into the ":version" or ":label" suffix
+func ssmParameterName(key string) (string, error) {
+	name, rawQuery, _ := strings.Cut(key, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", fmt.Errorf("invalid aws parameter selector %q: %w", rawQuery, err)
+	}
+	if len(query) > 1 {
+		return "", fmt.Errorf("aws parameter %q accepts only one of the version and label selectors", name)
+	}
+	for k := range query {
+		if k != "version" && k != "label" {
+			return "", fmt.Errorf("aws parameter selector %q not supported", k)
+		}
+		name += ":" + query.Get(k)
+	}
+
+	return name, nil
+}
+
+// ASMProvider resolves references against the AWS Secrets Manager.
+// A reference is the secret name or ARN, optionally followed by a query
+// selecting the version, e.g. "prod/db?versionStage=AWSPREVIOUS" or "prod/db?versionId=<id>"
+type ASMProvider struct {
+	Session *session.Session
+}
+
+// Resolve gets the value of the secret with the given name from the AWS Secrets Manager.
+// SecretString is returned when set, SecretBinary otherwise
+func (a *ASMProvider) Resolve(key string) (string, error) {
+	return a.ResolveContext(context.Background(), key)
+}
+
+// ResolveContext is Resolve with a context for the request
+func (a *ASMProvider) ResolveContext(ctx context.Context, key string) (string, error) {
+	if a.Session == nil {
+		return key, fmt.Errorf("aws connection is not set")
+	}
+
+	input, err := asmInput(key)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := secretsmanager.New(a.Session).GetSecretValueWithContext(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("err while get aws secret: %w", err)
+	}
+	if output.SecretString != nil {
+		return *output.SecretString, nil
+	}
+	if output.SecretBinary != nil {
+		return string(output.SecretBinary), nil
+	}
+
+	return "", fmt.Errorf("aws secret %q has no value", key)
+}
+
+// asmInput builds the GetSecretValue request for key
+func asmInput(key string) (*secretsmanager.GetSecretValueInput, error) {
+	name, rawQuery, _ := strings.Cut(key, "?")
+	input := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid aws secret selector %q: %w", rawQuery, err)
+	}
+	for k := range query {
+		switch k {
+		case "versionStage":
+			input.VersionStage = aws.String(query.Get(k))
+		case "versionId":
+			input.VersionId = aws.String(query.Get(k))
+		default:
+			return nil, fmt.Errorf("aws secret selector %q not supported", k)
+		}
+	}
+
+	return input, nil
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAzureKeyVaultAPIVersion = "7.4"
+	defaultAzureAuthorityHost      = "https://login.microsoftonline.com"
+	azureKeyVaultScope             = "https://vault.azure.net/.default"
+	azureTokenExpiryDelta          = time.Minute
+)
+
+// AzureTokenSource provides access tokens for the Key Vault REST API
+type AzureTokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// AzureTokenFunc allows using an ordinary function as an AzureTokenSource
+type AzureTokenFunc func(ctx context.Context) (string, error)
+
+// Token calls f(ctx)
+func (f AzureTokenFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// AzureClientCredentials obtains tokens for a service principal with the client credentials flow
+type AzureClientCredentials struct {
+	TenantID     string
+	ClientID     string
+	ClientSecret string
+	// AuthorityHost of the identity platform, "https://login.microsoftonline.com" by default
+	AuthorityHost string
+	HTTPClient    *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Token returns a cached token or requests a new one when it is about to expire
+func (a *AzureClientCredentials) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.token != "" && time.Now().Add(azureTokenExpiryDelta).Before(a.expiry) {
+		return a.token, nil
+	}
+
+	host := a.AuthorityHost
+	if host == "" {
+		host = defaultAzureAuthorityHost
+	}
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {a.ClientID},
+		"client_secret": {a.ClientSecret},
+		"scope":         {azureKeyVaultScope},
+	}
+
+	client := a.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(host, "/")+"/"+url.PathEscape(a.TenantID)+"/oauth2/v2.0/token",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("unable to create azure token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("azure token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err = decodeResponse(resp, &token); err != nil {
+		return "", fmt.Errorf("azure token request failed: %w", err)
+	}
+	a.token = token.AccessToken
+	a.expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+
+	return a.token, nil
+}
+
+// AzureKeyVaultClient talks to the Azure Key Vault REST API
+type AzureKeyVaultClient struct {
+	TokenSource AzureTokenSource
+	// VaultURL returns the base URL of the vault, "https://<name>.vault.azure.net" by default
+	VaultURL   func(vaultName string) string
+	APIVersion string
+	HTTPClient *http.Client
+}
+
+// NewAzureKeyVaultClient creates a new AzureKeyVaultClient
+func NewAzureKeyVaultClient(tokenSource AzureTokenSource) *AzureKeyVaultClient {
+	return &AzureKeyVaultClient{
+		TokenSource: tokenSource,
+		APIVersion:  defaultAzureKeyVaultAPIVersion,
+		HTTPClient:  http.DefaultClient,
+	}
+}
+
+// GetSecret gets the value of the secret from the vault. An empty version means the latest version
+func (c *AzureKeyVaultClient) GetSecret(vaultName, name, version string) (string, error) {
+	return c.GetSecretContext(context.Background(), vaultName, name, version)
+}
+
+// GetSecretContext is GetSecret with a context for the request
+func (c *AzureKeyVaultClient) GetSecretContext(ctx context.Context, vaultName, name, version string) (string, error) {
+	if c.TokenSource == nil {
+		return "", fmt.Errorf("azure token source is not set")
+	}
+	token, err := c.TokenSource.Token(ctx)
+	if err != nil {
+		return "", fmt.Errorf("unable to get azure token: %w", err)
+	}
+
+	baseURL := "https://" + vaultName + ".vault.azure.net"
+	if c.VaultURL != nil {
+		baseURL = c.VaultURL(vaultName)
+	}
+	apiVersion := c.APIVersion
+	if apiVersion == "" {
+		apiVersion = defaultAzureKeyVaultAPIVersion
+	}
+	endpoint := strings.TrimRight(baseURL, "/") + "/secrets/" + url.PathEscape(name)
+	if version != "" {
+		endpoint += "/" + url.PathEscape(version)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?api-version="+url.QueryEscape(apiVersion), http.NoBody)
+	if err != nil {
+		return "", fmt.Errorf("unable to create azure request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("azure request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var secret struct {
+		Value *string `json:"value"`
+	}
+	if err = decodeResponse(resp, &secret); err != nil {
+		return "", err
+	}
+	if secret.Value == nil {
+		return "", fmt.Errorf("azure secret %q has no value", name)
+	}
+
+	return *secret.Value, nil
+}
+
+// AzureKeyVaultProvider resolves references against Azure Key Vault.
+// A reference is "vault-name/secret-name" optionally followed by "/version" or "?version=<version>"
+type AzureKeyVaultProvider struct {
+	Client *AzureKeyVaultClient
+}
+
+// Resolve gets the secret from Azure Key Vault
+func (a *AzureKeyVaultProvider) Resolve(key string) (string, error) {
+	return a.ResolveContext(context.Background(), key)
+}
+
+// ResolveContext is Resolve with a context for the request
+func (a *AzureKeyVaultProvider) ResolveContext(ctx context.Context, key string) (string, error) {
+	if a.Client == nil {
+		return key, fmt.Errorf("azure connection is not set")
+	}
+
+	path, rawQuery, _ := strings.Cut(key, "?")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return "", fmt.Errorf("invalid azure secret reference %q, expected vault/secret[/version]", key)
+	}
+	var version string
+	if len(parts) == 3 {
+		version = parts[2]
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", fmt.Errorf("invalid azure secret selector %q: %w", rawQuery, err)
+	}
+	for k := range query {
+		if k != "version" || version != "" {
+			return "", fmt.Errorf("azure secret selector %q not supported", k)
+		}
+		version = query.Get(k)
+	}
+
+	value, err := a.Client.GetSecretContext(ctx, parts[0], parts[1], version)
+	if err != nil {
+		return "", fmt.Errorf("err while get azure secret: %w", err)
+	}
+
+	return value, nil
+}
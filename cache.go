@@ -0,0 +1,188 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SecretCache keeps resolved secrets across Parse calls, so parsers sharing it fetch every secret
+// once per TTL. Secrets that are not found are kept for NegativeTTL, other errors are not cached.
+// It is safe for concurrent use
+type SecretCache struct {
+	TTL         time.Duration
+	NegativeTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	result  secretResult
+	expires time.Time
+}
+
+// NewSecretCache creates a new SecretCache. A zero negativeTTL disables the caching of not found secrets
+func NewSecretCache(ttl, negativeTTL time.Duration) *SecretCache {
+	return &SecretCache{
+		TTL:         ttl,
+		NegativeTTL: negativeTTL,
+	}
+}
+
+// WithSecretCache makes the parser keep the resolved secrets in cache, which may be shared by several parsers
+func WithSecretCache(cache *SecretCache) Option {
+	return func(p *Parser) {
+		p.cache = cache
+	}
+}
+
+type bypassCacheKey struct{}
+
+// BypassCache returns a context making ParseContext fetch every secret again.
+// The fetched secrets still replace the cached ones
+func BypassCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassCacheKey{}).(bool)
+	return bypass
+}
+
+// Invalidate removes the secret ref points to from the cache
+func (c *SecretCache) Invalidate(ref Reference) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, cacheKey(ref))
+}
+
+// Purge removes all the secrets from the cache
+func (c *SecretCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = nil
+}
+
+// InvalidateSecret removes the secret the reference value points to from the parser cache,
+// e.g. "gcp:projects/p/secrets/s"
+func (p *Parser) InvalidateSecret(value string) error {
+	ref, _, ok, err := p.parseReference(value)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%q is not a secret reference", value)
+	}
+	if p.cache != nil {
+		p.cache.Invalidate(ref)
+	}
+
+	return nil
+}
+
+func (c *SecretCache) get(ref Reference) (secretResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[cacheKey(ref)]
+	if !ok || !c.clock().Before(entry.expires) {
+		return secretResult{}, false
+	}
+
+	return entry.result, true
+}
+
+func (c *SecretCache) put(ref Reference, result secretResult) {
+	ttl := c.TTL
+	if result.err != nil {
+		if !IsNotFound(result.err) {
+			return
+		}
+		ttl = c.NegativeTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = map[string]cacheEntry{}
+	}
+	c.entries[cacheKey(ref)] = cacheEntry{result: result, expires: c.clock().Add(ttl)}
+}
+
+func (c *SecretCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+
+	return time.Now()
+}
+
+// cacheKey returns the normalized reference to the secret: without the selector and with the query sorted
+func cacheKey(ref Reference) string {
+	ref = ref.secret()
+	if query, err := url.ParseQuery(ref.RawQuery); err == nil {
+		ref.RawQuery = query.Encode()
+	}
+
+	return ref.String()
+}
+
+// cachedSecret returns the secret ref points to from the parser cache
+func (p *Parser) cachedSecret(run *parseRun, ref Reference) (secretResult, bool) {
+	if p.cache == nil || cacheBypassed(run.ctx) {
+		return secretResult{}, false
+	}
+
+	return p.cache.get(ref)
+}
+
+// cacheSecret keeps the result of fetching the secret ref points to in the parser cache
+func (p *Parser) cacheSecret(ref Reference, result secretResult) {
+	if p.cache != nil {
+		p.cache.put(ref, result)
+	}
+}
+
+// IsNotFound reports whether err means that the secret does not exist
+func IsNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return true
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		return grpcErr.GRPCStatus().Code() == codes.NotFound
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		switch awsErr.Code() {
+		case "ParameterNotFound", "ParameterVersionNotFound", "ResourceNotFoundException":
+			return true
+		}
+		return false
+	}
+
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
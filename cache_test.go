@@ -0,0 +1,140 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParser_ParseCached(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := NewSecretCache(time.Minute, 10*time.Second)
+	cache.now = func() time.Time { return now }
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	count := func(ref string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls[ref]
+	}
+	errs := map[string]error{
+		"missing": fmt.Errorf("err while get secret: %w", status.Error(codes.NotFound, "not found")),
+		"broken":  errors.New("unavailable"),
+	}
+	provider := SecretProviderFunc(func(ref string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[ref]++
+		if err, ok := errs[ref]; ok {
+			return "", err
+		}
+		return fmt.Sprintf("%s-%d", ref, calls[ref]), nil
+	})
+	newParser := func(values map[string]string) Parser {
+		p := NewParser(nil, nil, WithSources(mapSource{name: "test", values: values}), WithSecretCache(cache))
+		p.RegisterProvider("c", provider)
+		return p
+	}
+
+	type config struct {
+		A string `config:"A"`
+		B string `config:"B"`
+	}
+	first := newParser(map[string]string{"A": "c:token", "B": "secret+c://db?y=2&x=1"})
+	second := newParser(map[string]string{"A": "secret+c://token", "B": "c:db?x=1&y=2"})
+	parse := func(ctx context.Context, p Parser) *config {
+		got := &config{}
+		require.NoError(t, p.ParseContext(ctx, got))
+		return got
+	}
+
+	require.Equal(t, &config{A: "token-1", B: "db?y=2&x=1-1"}, parse(context.Background(), first))
+	require.Equal(t, &config{A: "token-1", B: "db?y=2&x=1-1"}, parse(context.Background(), second))
+	require.Equal(t, 1, count("token"))
+
+	now = now.Add(time.Minute)
+	require.Equal(t, "token-2", parse(context.Background(), second).A)
+
+	require.NoError(t, first.InvalidateSecret("c:token"))
+	require.Equal(t, "token-3", parse(context.Background(), first).A)
+	require.Error(t, first.InvalidateSecret("plain value"))
+
+	require.Equal(t, "token-4", parse(BypassCache(context.Background()), first).A)
+	require.Equal(t, "token-4", parse(context.Background(), second).A)
+
+	cache.Purge()
+	require.Equal(t, "token-5", parse(context.Background(), first).A)
+
+	failing := newParser(map[string]string{"A": "c:missing", "B": "c:broken"})
+	for i := 0; i < 2; i++ {
+		require.Error(t, failing.Parse(&config{}))
+	}
+	require.Equal(t, 1, count("missing"))
+	require.Equal(t, 2, count("broken"))
+
+	now = now.Add(10 * time.Second)
+	require.Error(t, failing.Parse(&config{}))
+	require.Equal(t, 2, count("missing"))
+}
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "gcp",
+			err:  fmt.Errorf("err while get gcp secret %w", status.Error(codes.NotFound, "not found")),
+			want: true,
+		},
+		{
+			name: "gcp_unavailable",
+			err:  status.Error(codes.Unavailable, "unavailable"),
+		},
+		{
+			name: "ssm",
+			err:  awserr.NewRequestFailure(awserr.New("ParameterNotFound", "missing", nil), http.StatusBadRequest, "id"),
+			want: true,
+		},
+		{
+			name: "asm",
+			err:  awserr.NewRequestFailure(awserr.New("ResourceNotFoundException", "missing", nil), http.StatusBadRequest, "id"),
+			want: true,
+		},
+		{
+			name: "aws_access_denied",
+			err:  awserr.NewRequestFailure(awserr.New("AccessDeniedException", "denied", nil), http.StatusBadRequest, "id"),
+		},
+		{
+			name: "http",
+			err:  fmt.Errorf("err while get vault secret: %w", &statusError{StatusCode: http.StatusNotFound}),
+			want: true,
+		},
+		{
+			name: "file",
+			err:  fmt.Errorf("unable to read secret file: %w", fs.ErrNotExist),
+			want: true,
+		},
+		{
+			name: "other",
+			err:  errors.New("failed"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, IsNotFound(tt.err))
+		})
+	}
+}
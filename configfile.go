@@ -0,0 +1,181 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Supported configuration file formats
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	FormatTOML = "toml"
+)
+
+// ConfigFileSource looks up values in a YAML, JSON or TOML document.
+// Nested keys are joined with dots, so the document
+//
+//	db:
+//	  timeout: 5s
+//
+// provides "db.timeout", which is looked up for the field tagged "timeout"
+// inside a struct field tagged "db". Lists of scalars are joined with commas
+type ConfigFileSource struct {
+	Path string
+	// Format of the document, detected by the file extension when empty
+	Format string
+
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// NewConfigFileSource creates a ConfigFileSource and loads the file
+func NewConfigFileSource(path string) (*ConfigFileSource, error) {
+	c := &ConfigFileSource{Path: path}
+	if err := c.Load(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// WithConfigFile makes the parser look up keys missing in the previous sources in the
+// YAML, JSON or TOML file at path. The file is read again on every Parse
+func WithConfigFile(path string) Option {
+	return func(p *Parser) {
+		p.sources = append(p.sources, &ConfigFileSource{Path: path})
+	}
+}
+
+// Load reads the file again
+func (c *ConfigFileSource) Load() error {
+	data, err := os.ReadFile(filepath.Clean(c.Path))
+	if err != nil {
+		return fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	format := c.Format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(c.Path)), ".")
+	}
+
+	var doc map[string]interface{}
+	switch format {
+	case FormatYAML, "yml":
+		err = yaml.Unmarshal(data, &doc)
+	case FormatJSON:
+		err = json.Unmarshal(data, &doc)
+	case FormatTOML:
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return fmt.Errorf("config file format %q not supported", format)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to parse config file %s: %w", c.Path, err)
+	}
+
+	values := map[string]string{}
+	if err = flatten("", doc, values); err != nil {
+		return fmt.Errorf("unable to parse config file %s: %w", c.Path, err)
+	}
+
+	c.mu.Lock()
+	c.values = values
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Name returns "configfile:" followed by the path
+func (c *ConfigFileSource) Name() string {
+	return "configfile:" + c.Path
+}
+
+func (c *ConfigFileSource) watchTargets() []watchTarget {
+	return []watchTarget{newWatchTarget(c.Path)}
+}
+
+// Lookup returns the value for the dotted key
+func (c *ConfigFileSource) Lookup(key string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// flatten stores the scalar values of doc into values under dotted keys
+func flatten(prefix string, doc interface{}, values map[string]string) error {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if err := flatten(joinKey(prefix, key), item, values); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := scalarString(item)
+			if !ok {
+				return fmt.Errorf("key %s: only lists of scalar values are supported", prefix)
+			}
+			items = append(items, s)
+		}
+		values[prefix] = strings.Join(items, ",")
+	default:
+		s, ok := scalarString(v)
+		if !ok {
+			return fmt.Errorf("key %s: unsupported value %v", prefix, v)
+		}
+		values[prefix] = s
+	}
+
+	return nil
+}
+
+// scalarString formats a scalar document value the way it would be written in an environment variable
+func scalarString(v interface{}) (string, bool) {
+	switch s := v.(type) {
+	case nil:
+		return "", true
+	case string:
+		return s, true
+	case bool:
+		return strconv.FormatBool(s), true
+	case int:
+		return strconv.Itoa(s), true
+	case int64:
+		return strconv.FormatInt(s, 10), true
+	case uint64:
+		return strconv.FormatUint(s, 10), true
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), true
+	case time.Time:
+		return s.Format(time.RFC3339Nano), true
+	case fmt.Stringer:
+		return s.String(), true
+	}
+
+	return "", false
+}
+
+// joinKey joins a nested key to its parent prefix
+func joinKey(prefix, key string) string {
+	if key == "" {
+		return prefix
+	}
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ContextSecretProvider is a SecretProvider whose calls can be cancelled.
+// The parser passes it the context of ParseContext, limited by WithTimeout
+type ContextSecretProvider interface {
+	SecretProvider
+	ResolveContext(ctx context.Context, ref string) (string, error)
+}
+
+// PendingError is returned when the context is done before the secrets of all the fields are resolved
+type PendingError struct {
+	// Err is the error of the context
+	Err error
+	// Fields are the paths of the fields whose secrets were not resolved, in field order
+	Fields []string
+}
+
+func (e *PendingError) Error() string {
+	return fmt.Sprintf("parse interrupted: %v, pending fields: %s", e.Err, strings.Join(e.Fields, ", "))
+}
+
+func (e *PendingError) Unwrap() error {
+	return e.Err
+}
+
+// WithTimeout limits the time of every provider call. Without it a call may take as long as the parse context allows
+func WithTimeout(timeout time.Duration) Option {
+	return func(p *Parser) {
+		p.timeout = timeout
+	}
+}
+
+// ParseContext is Parse with a context passed to every provider call.
+// When the context is done, it returns a *PendingError listing the fields left without their secrets
+func (p *Parser) ParseContext(ctx context.Context, v interface{}) error {
+	return p.ParseWithFuncsContext(ctx, v, map[reflect.Type]ParserFunc{})
+}
+
+// resolveKey resolves the key of ref with provider under the per-call timeout and the retry policy
+func (p *Parser) resolveKey(ctx context.Context, provider SecretProvider, ref Reference) (string, error) {
+	n, rest, versioned, err := ref.versions()
+	if err != nil {
+		return "", err
+	}
+	if versioned {
+		return callProvider(ctx, p, ref.String(), func(ctx context.Context) (string, error) {
+			return resolveVersions(ctx, provider, rest.key(), n)
+		})
+	}
+
+	key := ref.key()
+	return callProvider(ctx, p, ref.String(), func(ctx context.Context) (string, error) {
+		if cp, ok := provider.(ContextSecretProvider); ok {
+			return cp.ResolveContext(ctx, key)
+		}
+		return provider.Resolve(key)
+	})
+}
+
+// resolveVersions returns the n latest versions of the secret as a JSON list of SecretVersion
+func resolveVersions(ctx context.Context, provider SecretProvider, key string, n int) (string, error) {
+	vp, ok := provider.(VersionedSecretProvider)
+	if !ok {
+		return "", fmt.Errorf("provider does not support secret versions")
+	}
+	versions, err := vp.ResolveVersions(ctx, key, n)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(versions)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// callContext calls fn with ctx limited by timeout, if set, and returns as soon as ctx is done,
+// even if fn ignores it. fn then finishes in the background
+func callContext[T any](ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (T, error)) (T, error) {
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn(ctx)
+		done <- result{value: value, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// fieldReference links a field to a secret it references
+type fieldReference struct {
+	field string
+	ref   Reference
+}
+
+// pendingFields returns the fields referencing secrets that were not resolved because the context is done
+func (run *parseRun) pendingFields() []string {
+	var fields []string
+	seen := map[string]bool{}
+	for _, fr := range run.references {
+		result, ok := run.secrets[fr.ref]
+		if ok && !errors.Is(result.err, context.Canceled) && !errors.Is(result.err, context.DeadlineExceeded) {
+			continue
+		}
+		if !seen[fr.field] {
+			seen[fr.field] = true
+			fields = append(fields, fr.field)
+		}
+	}
+
+	return fields
+}
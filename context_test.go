@@ -0,0 +1,114 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// contextKey is the type of the context values set by the tests
+type contextKey string
+
+// ctxProvider resolves references to a value of the context it is called with
+type ctxProvider struct{}
+
+func (ctxProvider) Resolve(string) (string, error) {
+	return "", errors.New("called without a context")
+}
+
+func (ctxProvider) ResolveContext(ctx context.Context, ref string) (string, error) {
+	value, _ := ctx.Value(contextKey(ref)).(string)
+	return value, nil
+}
+
+func TestParser_ParseContext(t *testing.T) {
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	hung := SecretProviderFunc(func(ref string) (string, error) {
+		if ref == "fast" {
+			return "fast", nil
+		}
+		<-release
+		return "late", nil
+	})
+
+	type config struct {
+		A      string `config:"A"`
+		B      string `config:"B"`
+		Nested struct {
+			C string `config:"C"`
+		} `config:"NESTED"`
+	}
+	source := mapSource{name: "test", values: map[string]string{
+		"A":        "hung:a",
+		"B":        "hung:fast",
+		"NESTED.C": "postgres://app:${hung:c}@db/app",
+	}}
+
+	tests := []struct {
+		name        string
+		opts        []Option
+		timeout     time.Duration
+		wantPending []string
+		wantErr     error
+	}{
+		{
+			name:        "deadline",
+			timeout:     50 * time.Millisecond,
+			wantPending: []string{"A", "Nested.C"},
+			wantErr:     context.DeadlineExceeded,
+		},
+		{
+			name:    "per_call_timeout",
+			opts:    []Option{WithTimeout(20 * time.Millisecond)},
+			timeout: time.Minute,
+			wantErr: context.DeadlineExceeded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser(nil, nil, append([]Option{WithSources(source)}, tt.opts...)...)
+			p.RegisterProvider("hung", hung)
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := p.ParseContext(ctx, &config{})
+			require.Less(t, time.Since(start), 5*time.Second)
+			require.ErrorIs(t, err, tt.wantErr)
+
+			var pending *PendingError
+			if tt.wantPending == nil {
+				require.False(t, errors.As(err, &pending))
+				return
+			}
+			require.ErrorAs(t, err, &pending)
+			require.Equal(t, tt.wantPending, pending.Fields)
+		})
+	}
+}
+
+func TestParser_ParseContextProvider(t *testing.T) {
+	p := NewParser(nil, nil, WithSources(mapSource{name: "test", values: map[string]string{
+		"TOKEN": "ctx:token",
+	}}))
+	p.RegisterProvider("ctx", ctxProvider{})
+
+	got := &struct {
+		Token string `config:"TOKEN"`
+	}{}
+	ctx := context.WithValue(context.Background(), contextKey("token"), "from-context")
+	require.NoError(t, p.ParseContext(ctx, got))
+	require.Equal(t, "from-context", got.Token)
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err := p.ParseContext(ctx, got)
+	require.ErrorIs(t, err, context.Canceled)
+	var pending *PendingError
+	require.ErrorAs(t, err, &pending)
+	require.Equal(t, []string{"Token"}, pending.Fields)
+}
@@ -0,0 +1,322 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DotenvSource looks up values in .env files without touching the process environment.
+// Files are layered in order, so values from later files override earlier ones,
+// e.g. ".env" then ".env.local". Files that do not exist are skipped.
+//
+// Supported syntax: comments, an optional "export" prefix, single quoted literal values,
+// double quoted values with escapes and multiple lines, and ${VAR} or $VAR interpolation
+// in unquoted and double quoted values, resolved against the environment and the values defined before
+type DotenvSource struct {
+	Files []string
+
+	mu     sync.RWMutex
+	values map[string]string
+}
+
+// NewDotenvSource creates a DotenvSource and loads files
+func NewDotenvSource(files ...string) (*DotenvSource, error) {
+	d := &DotenvSource{Files: files}
+	if err := d.Load(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// WithDotenv makes the parser look up keys missing in the previous sources in the given .env files.
+// The files are read again on every Parse
+func WithDotenv(files ...string) Option {
+	return func(p *Parser) {
+		p.sources = append(p.sources, &DotenvSource{Files: files})
+	}
+}
+
+// Load reads the files again
+func (d *DotenvSource) Load() error {
+	values := map[string]string{}
+	for _, file := range d.Files {
+		data, err := os.ReadFile(filepath.Clean(file))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read dotenv file: %w", err)
+		}
+		if err = parseDotenv(string(data), values); err != nil {
+			return fmt.Errorf("unable to parse dotenv file %s: %w", file, err)
+		}
+	}
+
+	d.mu.Lock()
+	d.values = values
+	d.mu.Unlock()
+
+	return nil
+}
+
+// Name returns "dotenv:" followed by the files
+func (d *DotenvSource) Name() string {
+	return "dotenv:" + strings.Join(d.Files, ",")
+}
+
+func (d *DotenvSource) watchTargets() []watchTarget {
+	targets := make([]watchTarget, 0, len(d.Files))
+	for _, file := range d.Files {
+		targets = append(targets, newWatchTarget(file))
+	}
+
+	return targets
+}
+
+// Lookup returns the value for key from the loaded files
+func (d *DotenvSource) Lookup(key string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	value, ok := d.values[key]
+	return value, ok
+}
+
+// parseDotenv parses data and stores the values into values
+func parseDotenv(data string, values map[string]string) error {
+	s := &dotenvScanner{data: strings.ReplaceAll(data, "\r\n", "\n"), line: 1}
+	for {
+		s.skipBlank()
+		if s.eof() {
+			return nil
+		}
+		if s.peek() == '#' {
+			s.skipLine()
+			continue
+		}
+
+		key, err := s.key()
+		if err != nil {
+			return err
+		}
+		value, err := s.value(func(name string) string {
+			if v, ok := os.LookupEnv(name); ok {
+				return v
+			}
+			return values[name]
+		})
+		if err != nil {
+			return fmt.Errorf("key %s: %w", key, err)
+		}
+		values[key] = value
+	}
+}
+
+// dotenvScanner reads .env syntax
+type dotenvScanner struct {
+	data string
+	pos  int
+	line int
+}
+
+func (s *dotenvScanner) eof() bool {
+	return s.pos >= len(s.data)
+}
+
+func (s *dotenvScanner) peek() byte {
+	return s.data[s.pos]
+}
+
+func (s *dotenvScanner) next() byte {
+	c := s.data[s.pos]
+	s.pos++
+	if c == '\n' {
+		s.line++
+	}
+	return c
+}
+
+func (s *dotenvScanner) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", s.line, fmt.Sprintf(format, args...))
+}
+
+// skipBlank skips whitespace including newlines
+func (s *dotenvScanner) skipBlank() {
+	for !s.eof() && strings.IndexByte(" \t\n", s.peek()) >= 0 {
+		s.next()
+	}
+}
+
+// skipSpaces skips whitespace on the current line
+func (s *dotenvScanner) skipSpaces() {
+	for !s.eof() && (s.peek() == ' ' || s.peek() == '\t') {
+		s.next()
+	}
+}
+
+func (s *dotenvScanner) skipLine() {
+	for !s.eof() && s.next() != '\n' {
+	}
+}
+
+// key reads "[export ]KEY=" and returns KEY
+func (s *dotenvScanner) key() (string, error) {
+	if strings.HasPrefix(s.data[s.pos:], "export ") || strings.HasPrefix(s.data[s.pos:], "export\t") {
+		s.pos += len("export")
+		s.skipSpaces()
+	}
+
+	start := s.pos
+	for !s.eof() && isDotenvKeyChar(s.peek()) {
+		s.next()
+	}
+	key := s.data[start:s.pos]
+	if key == "" {
+		return "", s.errorf("invalid key")
+	}
+
+	s.skipSpaces()
+	if s.eof() || s.peek() != '=' {
+		return "", s.errorf("expected '=' after key %s", key)
+	}
+	s.next()
+	s.skipSpaces()
+
+	return key, nil
+}
+
+// value reads the value up to the end of the line, expanding variables with lookup
+func (s *dotenvScanner) value(lookup func(string) string) (string, error) {
+	if s.eof() {
+		return "", nil
+	}
+
+	var value string
+	switch s.peek() {
+	case '\'':
+		s.next()
+		end := strings.IndexByte(s.data[s.pos:], '\'')
+		if end < 0 {
+			return "", s.errorf("unterminated single quoted value")
+		}
+		value = s.data[s.pos : s.pos+end]
+		for i := 0; i <= end; i++ {
+			s.next()
+		}
+	case '"':
+		s.next()
+		var err error
+		if value, err = s.doubleQuoted(lookup); err != nil {
+			return "", err
+		}
+	default:
+		start := s.pos
+		for !s.eof() && s.peek() != '\n' {
+			if s.peek() == '#' && s.pos > start && (s.data[s.pos-1] == ' ' || s.data[s.pos-1] == '\t') {
+				break
+			}
+			s.next()
+		}
+		return expandDotenv(strings.TrimSpace(s.data[start:s.pos]), lookup), nil
+	}
+
+	s.skipSpaces()
+	if !s.eof() && s.peek() != '\n' && s.peek() != '#' {
+		return "", s.errorf("unexpected characters after quoted value")
+	}
+	s.skipLine()
+
+	return value, nil
+}
+
+// doubleQuoted reads a double quoted value after the opening quote
+func (s *dotenvScanner) doubleQuoted(lookup func(string) string) (string, error) {
+	var b strings.Builder
+	var raw strings.Builder
+	flush := func() {
+		b.WriteString(expandDotenv(raw.String(), lookup))
+		raw.Reset()
+	}
+
+	for !s.eof() {
+		c := s.next()
+		switch c {
+		case '"':
+			flush()
+			return b.String(), nil
+		case '\\':
+			if s.eof() {
+				return "", s.errorf("unterminated double quoted value")
+			}
+			e := s.next()
+			switch e {
+			case 'n':
+				raw.WriteByte('\n')
+			case 'r':
+				raw.WriteByte('\r')
+			case 't':
+				raw.WriteByte('\t')
+			case '$':
+				flush()
+				b.WriteByte('$')
+			case '"', '\\':
+				raw.WriteByte(e)
+			default:
+				raw.WriteByte('\\')
+				raw.WriteByte(e)
+			}
+		default:
+			raw.WriteByte(c)
+		}
+	}
+
+	return "", s.errorf("unterminated double quoted value")
+}
+
+// expandDotenv replaces ${VAR} and $VAR in value using lookup. The placeholders interpolated
+// by the parser, like ${VAR:-default} or ${gcp:secret}, and the escaped "$${" are kept
+func expandDotenv(value string, lookup func(string) string) string {
+	var b strings.Builder
+	rest := value
+	for {
+		i := strings.Index(rest, placeholderStart)
+		if i < 0 {
+			b.WriteString(os.Expand(rest, lookup))
+			break
+		}
+		if i > 0 && rest[i-1] == '$' {
+			b.WriteString(os.Expand(rest[:i-1], lookup))
+			b.WriteString("$" + placeholderStart)
+			rest = rest[i+len(placeholderStart):]
+			continue
+		}
+
+		end := placeholderLen(rest[i:])
+		if end < 0 {
+			b.WriteString(os.Expand(rest[:i], lookup))
+			b.WriteString(rest[i:])
+			break
+		}
+		end += i
+		if isVarName(rest[i+len(placeholderStart) : end-len(placeholderEnd)]) {
+			b.WriteString(os.Expand(rest[:end], lookup))
+		} else {
+			b.WriteString(os.Expand(rest[:i], lookup))
+			b.WriteString(rest[i:end])
+		}
+		rest = rest[end:]
+	}
+
+	return b.String()
+}
+
+func isDotenvKeyChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDotenv(t *testing.T) {
+	require.NoError(t, os.Setenv("DOTENV_TEST_HOST", "envhost"))
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "plain",
+			data: "A=1\nB = two words \n\n# comment\nC=",
+			want: map[string]string{"A": "1", "B": "two words", "C": ""},
+		},
+		{
+			name: "export",
+			data: "export A=1\nexport\tB=2",
+			want: map[string]string{"A": "1", "B": "2"},
+		},
+		{
+			name: "inline_comment",
+			data: "A=value # comment\nB=pass#word\nC=\"quoted\" # comment",
+			want: map[string]string{"A": "value", "B": "pass#word", "C": "quoted"},
+		},
+		{
+			name: "single_quoted",
+			data: "A='${B} \\n literal'\nB='multi\nline'",
+			want: map[string]string{"A": "${B} \\n literal", "B": "multi\nline"},
+		},
+		{
+			name: "double_quoted",
+			data: "A=\"line1\\nline2\\t\\\"quoted\\\" \\$HOME\"\nB=\"multi\nline\"",
+			want: map[string]string{"A": "line1\nline2\t\"quoted\" $HOME", "B": "multi\nline"},
+		},
+		{
+			name: "interpolation",
+			data: "DOTENV_USER=app\nURL=postgres://${DOTENV_USER}@$DOTENV_TEST_HOST/db\nQ=\"${DOTENV_USER}-x\"\nMISSING=${DOTENV_TEST_MISSING}",
+			want: map[string]string{"DOTENV_USER": "app", "URL": "postgres://app@envhost/db", "Q": "app-x", "MISSING": ""},
+		},
+		{
+			name: "parser_placeholders",
+			data: "REDIS=redis://${DOTENV_TEST_MISSING:-localhost}:6379/0\nPG=\"postgres://app:${custom:pw}@${DOTENV_TEST_HOST}/app\"\nESCAPED=$${DOTENV_TEST_HOST}\nOPEN=${DOTENV_TEST_HOST",
+			want: map[string]string{
+				"REDIS":   "redis://${DOTENV_TEST_MISSING:-localhost}:6379/0",
+				"PG":      "postgres://app:${custom:pw}@envhost/app",
+				"ESCAPED": "$${DOTENV_TEST_HOST}",
+				"OPEN":    "${DOTENV_TEST_HOST",
+			},
+		},
+		{
+			name: "crlf",
+			data: "A=1\r\nB=2\r\n",
+			want: map[string]string{"A": "1", "B": "2"},
+		},
+		{
+			name:    "missing_equals",
+			data:    "A 1",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated_double_quote",
+			data:    "A=\"value",
+			wantErr: true,
+		},
+		{
+			name:    "unterminated_single_quote",
+			data:    "A='value",
+			wantErr: true,
+		},
+		{
+			name:    "trailing_characters",
+			data:    "A=\"value\" other",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			err := parseDotenv(tt.data, got)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParser_ParseDotenv(t *testing.T) {
+	dir := t.TempDir()
+	env := filepath.Join(dir, ".env")
+	local := filepath.Join(dir, ".env.local")
+	require.NoError(t, os.WriteFile(env, []byte("DOTENV_HOST=localhost\nDOTENV_TIMEOUT=5s\nDOTENV_POSTGRES=postgres://username:password@${DOTENV_HOST}:5432/database?sslmode=disable\n"), 0o600))
+	require.NoError(t, os.WriteFile(local, []byte("DOTENV_HOST=db\nDOTENV_TIMEOUT=10s\n"), 0o600))
+
+	p := NewParser(nil, nil, WithDotenv(env, local, filepath.Join(dir, ".env.missing")))
+	got := &struct {
+		Timeout  time.Duration `config:"DOTENV_TIMEOUT"`
+		Host     string        `config:"DOTENV_HOST"`
+		Postgres `config:"DOTENV_POSTGRES"`
+	}{}
+	require.NoError(t, p.Parse(got))
+	require.Equal(t, 10*time.Second, got.Timeout)
+	require.Equal(t, "db", got.Host)
+	require.Equal(t, []string{"localhost:5432"}, got.Postgres.HostPort)
+	_, ok := os.LookupEnv("DOTENV_HOST")
+	require.False(t, ok)
+
+	require.NoError(t, os.WriteFile(local, []byte("DOTENV_TIMEOUT=1m\n"), 0o600))
+	require.NoError(t, p.Parse(got))
+	require.Equal(t, time.Minute, got.Timeout)
+
+	require.NoError(t, os.WriteFile(local, []byte("DOTENV_TIMEOUT\n"), 0o600))
+	require.Error(t, p.Parse(got))
+}
+
+func TestParser_ParseDotenvPlaceholders(t *testing.T) {
+	env := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(env, []byte(
+		"DOTENV_REDIS=redis://${DOTENV_REDIS_HOST:-localhost}:6379/0\n"+
+			"DOTENV_PG=postgres://app:${custom:pw}@db:5432/app\n"+
+			"DOTENV_LITERAL=$${DOTENV_REDIS_HOST}\n"), 0o600))
+
+	p := NewParser(nil, nil, WithDotenv(env), WithExpansion())
+	p.RegisterProvider("custom", SecretProviderFunc(func(ref string) (string, error) { return "p@ss", nil }))
+	got := &struct {
+		Redis    string `config:"DOTENV_REDIS"`
+		Postgres string `config:"DOTENV_PG"`
+		Literal  string `config:"DOTENV_LITERAL"`
+	}{}
+	require.NoError(t, p.Parse(got))
+	require.Equal(t, "redis://localhost:6379/0", got.Redis)
+	require.Equal(t, "postgres://app:p%40ss@db:5432/app", got.Postgres)
+	require.Equal(t, "${DOTENV_REDIS_HOST}", got.Literal)
+}
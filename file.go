@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileScheme is the scheme of the built-in FileProvider
+const fileScheme = "file"
+
+// FileProvider resolves references by reading the file at the referenced path.
+// Trailing newlines are trimmed
+type FileProvider struct{}
+
+// Resolve reads the file at path
+func (FileProvider) Resolve(path string) (string, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return "", fmt.Errorf("err while read file: %w", err)
+	}
+
+	return trimNewlines(string(data)), nil
+}
+
+// DirSource looks up values in a directory holding one file per key,
+// like secrets mounted by Kubernetes or Docker under /run/secrets
+type DirSource struct {
+	Dir string
+}
+
+// Name returns "dir:" followed by the directory
+func (d DirSource) Name() string {
+	return "dir:" + d.Dir
+}
+
+func (d DirSource) watchTargets() []watchTarget {
+	dir := d.Dir
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	return []watchTarget{{dir: dir}}
+}
+
+// Lookup reads the file named by key in the directory
+func (d DirSource) Lookup(key string) (string, bool) {
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return "", false
+	}
+
+	path := filepath.Join(d.Dir, key)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return "", false
+	}
+
+	return trimNewlines(string(data)), true
+}
+
+func trimNewlines(value string) string {
+	return strings.TrimRight(value, "\r\n")
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_ParseFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db_password"), []byte("password\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "jwt"), []byte("SigningKeyAT,SigningKeyRT\r\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "file_timeout"), []byte("5s\n"), 0o600))
+
+	tests := []struct {
+		name      string
+		args      interface{}
+		wantValue interface{}
+		wantErr   bool
+		testData  string
+		testKey   string
+	}{
+		{
+			name: "file_reference",
+			args: &struct {
+				TestStr string `config:"file_string"`
+			}{},
+			wantValue: &struct {
+				TestStr string `config:"file_string"`
+			}{TestStr: "password"},
+			testData: "secret+file://" + filepath.Join(dir, "db_password"),
+			testKey:  "file_string",
+		},
+		{
+			name: "file_reference_jwt",
+			args: &struct {
+				JWT `config:"file_string"`
+			}{},
+			wantValue: &struct {
+				JWT `config:"file_string"`
+			}{JWT{SigningKeyAT: "SigningKeyAT", SigningKeyRT: "SigningKeyRT"}},
+			testData: "secret+file://" + filepath.Join(dir, "jwt"),
+			testKey:  "file_string",
+		},
+		{
+			name: "file_reference_missing",
+			args: &struct {
+				TestStr string `config:"file_string"`
+			}{},
+			wantErr:  true,
+			testData: "secret+file://" + filepath.Join(dir, "missing"),
+			testKey:  "file_string",
+		},
+		{
+			name: "file_reference_short_form",
+			args: &struct {
+				TestStr string `config:"file_string"`
+			}{},
+			wantValue: &struct {
+				TestStr string `config:"file_string"`
+			}{TestStr: "password"},
+			testData: "file:" + filepath.Join(dir, "db_password"),
+			testKey:  "file_string",
+		},
+		{
+			name: "file_escaped",
+			args: &struct {
+				TestStr string `config:"file_string"`
+			}{},
+			wantValue: &struct {
+				TestStr string `config:"file_string"`
+			}{TestStr: "file:test.db?cache=shared&mode=memory"},
+			testData: `\file:test.db?cache=shared&mode=memory`,
+			testKey:  "file_string",
+		},
+		{
+			name: "secrets_dir",
+			args: &struct {
+				Password string        `config:"db_password"`
+				Timeout  time.Duration `config:"file_timeout"`
+			}{},
+			wantValue: &struct {
+				Password string        `config:"db_password"`
+				Timeout  time.Duration `config:"file_timeout"`
+			}{Password: "password", Timeout: 5 * time.Second},
+		},
+		{
+			name: "secrets_dir_environment_first",
+			args: &struct {
+				Timeout time.Duration `config:"file_timeout"`
+			}{},
+			wantValue: &struct {
+				Timeout time.Duration `config:"file_timeout"`
+			}{Timeout: time.Second},
+			testData: "1s",
+			testKey:  "file_timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.testKey != "" {
+				err := os.Setenv(tt.testKey, tt.testData)
+				require.NoError(t, err)
+				defer os.Unsetenv(tt.testKey)
+			}
+			p := NewParser(nil, nil, WithSecretsDir(dir))
+			gotErr := p.Parse(tt.args)
+			if tt.wantErr {
+				require.Error(t, gotErr, "")
+			} else {
+				require.NoError(t, gotErr)
+				require.EqualValues(t, tt.wantValue, tt.args)
+			}
+		})
+	}
+}
+
+func TestDirSource_Lookup(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key"), []byte("value\n\n"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o700))
+
+	tests := []struct {
+		name   string
+		key    string
+		want   string
+		wantOk bool
+	}{
+		{name: "file", key: "key", want: "value", wantOk: true},
+		{name: "missing", key: "missing"},
+		{name: "directory", key: "nested"},
+		{name: "path_traversal", key: "../key"},
+		{name: "empty", key: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DirSource{Dir: dir}.Lookup(tt.key)
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
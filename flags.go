@@ -0,0 +1,145 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// flagValue holds the value of a flag generated from a config tag.
+// It implements flag.Value and pflag.Value
+type flagValue struct {
+	field   reflect.StructField
+	parsers map[reflect.Type]ParserFunc
+	value   string
+	isSet   bool
+}
+
+// String returns the value given on the command line
+func (f *flagValue) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+// Set validates value with the parser of the field type and stores it
+func (f *flagValue) Set(value string) error {
+	tmp := reflect.New(f.field.Type).Elem()
+	if err := set(tmp, f.field, value, f.parsers); err != nil {
+		return err
+	}
+	f.value = value
+	f.isSet = true
+
+	return nil
+}
+
+// Type returns the name of the field type
+func (f *flagValue) Type() string {
+	return f.field.Type.String()
+}
+
+// IsBoolFlag allows boolean flags without a value
+func (f *flagValue) IsBoolFlag() bool {
+	return f.field.Type.Kind() == reflect.Bool
+}
+
+// RegisterFlags defines a flag in fs for every config tag of the struct v points to.
+// The flag name is the key, lowercased with underscores replaced by dashes and nested
+// keys joined with dots, e.g. "db.timeout". The usage is taken from the description tag.
+// Flags given on the command line take precedence over all other values in Parse
+func (p *Parser) RegisterFlags(fs *flag.FlagSet, v interface{}) error {
+	return p.RegisterFlagsWithFuncs(fs, v, map[reflect.Type]ParserFunc{})
+}
+
+// RegisterFlagsWithFuncs defines flags like RegisterFlags, validating values with ParserFunc
+func (p *Parser) RegisterFlagsWithFuncs(fs *flag.FlagSet, v interface{}, funcMap map[reflect.Type]ParserFunc) error {
+	return p.registerFlags(v, funcMap, func(name, usage string, value *flagValue) {
+		fs.Var(value, name, usage)
+	})
+}
+
+// RegisterPFlags defines flags like RegisterFlags in a pflag.FlagSet
+func (p *Parser) RegisterPFlags(fs *pflag.FlagSet, v interface{}) error {
+	return p.RegisterPFlagsWithFuncs(fs, v, map[reflect.Type]ParserFunc{})
+}
+
+// RegisterPFlagsWithFuncs defines flags like RegisterFlags in a pflag.FlagSet, validating values with ParserFunc
+func (p *Parser) RegisterPFlagsWithFuncs(fs *pflag.FlagSet, v interface{}, funcMap map[reflect.Type]ParserFunc) error {
+	return p.registerFlags(v, funcMap, func(name, usage string, value *flagValue) {
+		f := fs.VarPF(value, name, "", usage)
+		if value.IsBoolFlag() {
+			f.NoOptDefVal = "true"
+		}
+	})
+}
+
+func (p *Parser) registerFlags(v interface{}, funcMap map[reflect.Type]ParserFunc, define func(name, usage string, value *flagValue)) error {
+	refType := reflect.TypeOf(v)
+	if refType == nil || refType.Kind() != reflect.Ptr || refType.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("presented object is not a pointer to a struct")
+	}
+	parsers := defaultTypeParsers()
+	for k, v := range funcMap {
+		parsers[k] = v
+	}
+	if p.flags == nil {
+		p.flags = map[string]*flagValue{}
+	}
+
+	walkKeys(refType.Elem(), "", parsers, func(key string, sf reflect.StructField) {
+		value := &flagValue{field: sf, parsers: parsers}
+		p.flags[key] = value
+		define(flagName(key), sf.Tag.Get("description"), value)
+	})
+
+	return nil
+}
+
+// flagSource looks up the values of the generated flags set on the command line
+type flagSource struct {
+	flags map[string]*flagValue
+}
+
+// Lookup returns the value of the flag generated for the dotted key, if it was set
+func (f flagSource) Lookup(key string) (string, bool) {
+	if value, ok := f.flags[key]; ok && value.isSet {
+		return value.value, true
+	}
+	return "", false
+}
+
+// Name returns "flags"
+func (flagSource) Name() string {
+	return "flags"
+}
+
+// walkKeys calls fn for every field of refType holding a value, with its dotted key.
+// Struct fields without a parser are walked recursively like parseConfig does
+func walkKeys(refType reflect.Type, prefix string, parsers map[reflect.Type]ParserFunc, fn func(key string, sf reflect.StructField)) {
+	for i := 0; i < refType.NumField(); i++ {
+		sf := refType.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key := strings.Split(sf.Tag.Get("config"), ",")[0]
+
+		_, hasParser := parsers[sf.Type]
+		if sf.Type.Kind() == reflect.Struct && !hasParser {
+			walkKeys(sf.Type, joinKey(prefix, key), parsers, fn)
+			continue
+		}
+		if key != "" {
+			fn(joinKey(prefix, key), sf)
+		}
+	}
+}
+
+// flagName returns the flag name for a dotted key
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net/url"
+	"path"
+	"strings"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"google.golang.org/api/iterator"
+)
+
+const gcpLatestVersion = "latest"
+
+// GCPProvider resolves references against the GCP secret manager.
+// A reference is the secret name "projects/p/secrets/s" optionally followed by
+// "/versions/<version>" or the "?version=<version>" selector, where version is a number
+// or an alias such as "latest". Without a version the latest one is used
+type GCPProvider struct {
+	Client *secretmanager.Client
+}
+
+// Resolve accesses the secret version with the given name and returns its payload
+func (g *GCPProvider) Resolve(key string) (string, error) {
+	return g.ResolveContext(context.Background(), key)
+}
+
+// ResolveContext is Resolve with a context for the request
+func (g *GCPProvider) ResolveContext(ctx context.Context, key string) (string, error) {
+	if g.Client == nil {
+		return key, fmt.Errorf("gcp connection is not set")
+	}
+
+	name, err := gcpVersionName(key)
+	if err != nil {
+		return "", err
+	}
+	req := &secretmanagerpb.AccessSecretVersionRequest{
+		Name: name,
+	}
+
+	return g.access(ctx, req)
+}
+
+// ResolveVersions returns the n latest enabled versions of the secret, newest first,
+// i.e. "versions/latest", "versions/latest-1" and so on. The version number is the version ID
+func (g *GCPProvider) ResolveVersions(ctx context.Context, key string, n int) ([]SecretVersion, error) {
+	if g.Client == nil {
+		return nil, fmt.Errorf("gcp connection is not set")
+	}
+	name := strings.Trim(key, "/")
+	if strings.ContainsAny(name, "?") || strings.Contains(name, "/versions/") {
+		return nil, fmt.Errorf("gcp secret %q with versions must not select a version", key)
+	}
+
+	it := g.Client.ListSecretVersions(ctx, &secretmanagerpb.ListSecretVersionsRequest{
+		Parent: name,
+		Filter: "state:ENABLED",
+	})
+	var versions []SecretVersion
+	for len(versions) < n {
+		version, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("err while list gcp secret versions %w", err)
+		}
+
+		value, err := g.access(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: version.GetName()})
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, SecretVersion{
+			ID:        path.Base(version.GetName()),
+			Value:     value,
+			CreatedAt: version.GetCreateTime().AsTime(),
+		})
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("gcp secret %q has no enabled versions", name)
+	}
+
+	return versions, nil
+}
+
+// access returns the payload of the secret version, verifying its checksum
+func (g *GCPProvider) access(ctx context.Context, req *secretmanagerpb.AccessSecretVersionRequest) (string, error) {
+	output, err := g.Client.AccessSecretVersion(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("err while get gcp secret %w", err)
+	}
+
+	data := output.GetPayload().GetData()
+	if checksum := output.GetPayload().DataCrc32C; checksum != nil {
+		if int64(crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli))) != *checksum {
+			return "", fmt.Errorf("gcp secret %q payload is corrupted: checksum mismatch", output.GetName())
+		}
+	}
+
+	return string(data), nil
+}
+
+// gcpVersionName returns the full secret version name for key
+func gcpVersionName(key string) (string, error) {
+	name, rawQuery, _ := strings.Cut(key, "?")
+	name = strings.Trim(name, "/")
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", fmt.Errorf("invalid gcp secret selector %q: %w", rawQuery, err)
+	}
+	version := gcpLatestVersion
+	for k := range query {
+		if k != "version" {
+			return "", fmt.Errorf("gcp secret selector %q not supported", k)
+		}
+		version = query.Get(k)
+	}
+
+	if strings.Contains(name, "/versions/") {
+		if query.Has("version") {
+			return "", fmt.Errorf("gcp secret %q has both a version in the name and a version selector", name)
+		}
+		return name, nil
+	}
+
+	return name + "/versions/" + version, nil
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	placeholderStart = "${"
+	placeholderEnd   = "}"
+)
+
+// WithExpansion makes the parser expand ${VAR}, ${VAR:-default} and ${VAR:?error} in values,
+// e.g. "redis://${REDIS_HOST:-localhost}:6379/0". Variables are looked up in the parser
+// sources like config tags and are expanded recursively
+func WithExpansion() Option {
+	return func(p *Parser) {
+		p.expandVars = true
+	}
+}
+
+// interpolate replaces the ${<reference>} placeholders in value with the resolved secrets,
+// e.g. "postgres://app:${gcp:pg-password}@db:5432/app", and, with WithExpansion, the
+// ${VAR} placeholders with the variable values. When value is a URL the resolved secrets
+// are percent-encoded, so they can hold any character. Other placeholders are kept, and
+// "$${" is written as a literal "${". found reports whether any placeholder was replaced
+func (p *Parser) interpolate(run *parseRun, value string) (result string, found bool, err error) {
+	return p.expand(run, value, strings.Contains(value, "://"), nil)
+}
+
+// expand replaces the placeholders in value. stack holds the variables being expanded
+func (p *Parser) expand(run *parseRun, value string, escape bool, stack []string) (result string, found bool, err error) {
+	if !strings.Contains(value, placeholderStart) {
+		return value, false, nil
+	}
+
+	var b strings.Builder
+	rest := value
+	for {
+		i := strings.Index(rest, placeholderStart)
+		if i < 0 {
+			b.WriteString(rest)
+			break
+		}
+		if i > 0 && rest[i-1] == '$' {
+			b.WriteString(rest[:i-1])
+			b.WriteString(placeholderStart)
+			rest = rest[i+len(placeholderStart):]
+			continue
+		}
+		b.WriteString(rest[:i])
+		rest = rest[i:]
+
+		end := placeholderLen(rest)
+		if end < 0 {
+			b.WriteString(rest)
+			break
+		}
+		content := rest[len(placeholderStart) : end-len(placeholderEnd)]
+
+		replacement, ok, err := p.expandPlaceholder(run, content, escape, stack)
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			b.WriteString(replacement)
+			found = true
+		} else {
+			b.WriteString(rest[:end])
+		}
+		rest = rest[end:]
+	}
+
+	return b.String(), found, nil
+}
+
+// expandPlaceholder returns the replacement for the placeholder content, ok is false when it should be kept
+func (p *Parser) expandPlaceholder(run *parseRun, content string, escape bool, stack []string) (string, bool, error) {
+	ref, _, ok, err := p.parseReference(content)
+	if err != nil {
+		return "", false, err
+	}
+	if ok {
+		resolved, err := p.resolve(run, ref)
+		if err != nil {
+			return "", false, fmt.Errorf("while resolving %s%s%s: %w", placeholderStart, content, placeholderEnd, err)
+		}
+		if escape {
+			resolved = escapeURLComponent(resolved)
+		}
+		return resolved, true, nil
+	}
+	if !p.expandVars {
+		return "", false, nil
+	}
+
+	name, operator, operand := content, "", ""
+	if i := strings.Index(content, ":"); i >= 0 && i+1 < len(content) && (content[i+1] == '-' || content[i+1] == '?') {
+		name, operator, operand = content[:i], content[i:i+2], content[i+2:]
+	}
+	if !isVarName(name) {
+		return "", false, nil
+	}
+	for _, visiting := range stack {
+		if visiting == name {
+			return "", false, fmt.Errorf("variable expansion cycle: %s -> %s", strings.Join(stack, " -> "), name)
+		}
+	}
+	stack = append(stack, name)
+
+	value, _, _, _ := p.lookup("", name)
+	if value == "" {
+		switch operator {
+		case ":-":
+			value, _, err = p.expand(run, operand, escape, stack)
+			return value, true, err
+		case ":?":
+			message, _, err := p.expand(run, operand, escape, stack)
+			if err != nil {
+				return "", false, err
+			}
+			if message == "" {
+				message = "not set"
+			}
+			return "", false, fmt.Errorf("variable %s: %s", name, message)
+		}
+		return "", true, nil
+	}
+
+	ref, literal, ok, err := p.parseReference(value)
+	if err != nil {
+		return "", false, fmt.Errorf("variable %s: %w", name, err)
+	}
+	if ok {
+		return p.expandPlaceholder(run, ref.String(), escape, stack)
+	}
+	if literal != value {
+		return literal, true, nil
+	}
+	value, _, err = p.expand(run, value, escape, stack)
+
+	return value, true, err
+}
+
+// placeholderLen returns the length of the placeholder s starts with, counting nested
+// placeholders, or -1 when it is not terminated
+func placeholderLen(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], placeholderStart):
+			depth++
+			i += len(placeholderStart) - 1
+		case strings.HasPrefix(s[i:], placeholderEnd):
+			depth--
+			if depth == 0 {
+				return i + len(placeholderEnd)
+			}
+		}
+	}
+
+	return -1
+}
+
+// isVarName reports whether name is a valid variable name
+func isVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isDotenvKeyChar(name[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// escapeURLComponent percent-encodes every byte of s except the unreserved characters,
+// which makes it safe in the user info, host, path and query of a URL
+func escapeURLComponent(s string) string {
+	const hex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '.' || c == '_' || c == '~' {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xF])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_interpolate(t *testing.T) {
+	p := NewParser(nil, nil)
+	p.RegisterProvider("custom", SecretProviderFunc(func(ref string) (string, error) {
+		switch ref {
+		case "pg-password":
+			return "p@ss:w/rd?#%", nil
+		case "creds":
+			return `{"user":"app"}`, nil
+		case "token":
+			return "a b+c", nil
+		}
+		return "", fmt.Errorf("secret %s not found", ref)
+	}))
+
+	tests := []struct {
+		name      string
+		value     string
+		want      string
+		wantFound bool
+		wantErr   bool
+	}{
+		{
+			name:      "url_escaped",
+			value:     "postgres://app:${custom:pg-password}@db:5432/app?sslmode=require",
+			want:      "postgres://app:p%40ss%3Aw%2Frd%3F%23%25@db:5432/app?sslmode=require",
+			wantFound: true,
+		},
+		{
+			name:      "selector",
+			value:     "postgres://${custom:creds#user}:${secret+custom://pg-password}@db/app",
+			want:      "postgres://app:p%40ss%3Aw%2Frd%3F%23%25@db/app",
+			wantFound: true,
+		},
+		{
+			name:      "not_a_url",
+			value:     "Bearer ${custom:token}",
+			want:      "Bearer a b+c",
+			wantFound: true,
+		},
+		{
+			name:  "not_a_reference",
+			value: "redis://${REDIS_HOST}:6379",
+			want:  "redis://${REDIS_HOST}:6379",
+		},
+		{
+			name:  "escaped_placeholder",
+			value: "price $${custom:token}",
+			want:  "price ${custom:token}",
+		},
+		{
+			name:  "unterminated",
+			value: "value ${custom:token",
+			want:  "value ${custom:token",
+		},
+		{
+			name:    "missing_secret",
+			value:   "postgres://app:${custom:missing}@db/app",
+			wantErr: true,
+		},
+		{
+			name:    "invalid_reference",
+			value:   "postgres://app:${custom:}@db/app",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := &parseRun{ctx: context.Background(), secrets: map[Reference]secretResult{}}
+			got, found, err := p.interpolate(run, tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantFound, found)
+		})
+	}
+}
+
+func TestParser_ParseInterpolation(t *testing.T) {
+	p := NewParser(nil, nil)
+	p.RegisterProvider("custom", SecretProviderFunc(func(ref string) (string, error) {
+		return "p@ss/word", nil
+	}))
+	require.NoError(t, os.Setenv("INTERPOLATE_POSTGRES", "postgres://app:${custom:pg-password}@db:5432/app?sslmode=require"))
+
+	got := &struct {
+		Postgres `config:"INTERPOLATE_POSTGRES"`
+	}{}
+	require.NoError(t, p.Parse(got))
+	require.Equal(t, Postgres{
+		Username: "app",
+		Password: "p@ss/word",
+		HostPort: []string{"db:5432"},
+		Database: "app",
+		Sslmode:  "require",
+	}, got.Postgres)
+	require.Equal(t, "postgres://app:${custom:pg-password}@db:5432/app?sslmode=require", p.Origins()[0].Reference)
+}
+
+func TestParser_expand(t *testing.T) {
+	p := NewParser(nil, nil, WithSources(mapSource{name: "vars", values: map[string]string{
+		"REDIS_HOST": "cache",
+		"DB_HOST":    "db",
+		"DB_ADDR":    "${DB_HOST}:5432",
+		"EMPTY":      "",
+		"PASSWORD":   "custom:pg-password",
+		"LOOP_A":     "${LOOP_B}",
+		"LOOP_B":     "${LOOP_A}",
+		"SELF":       "x${SELF}",
+	}}), WithExpansion())
+	p.RegisterProvider("custom", SecretProviderFunc(func(ref string) (string, error) {
+		return "p@ss", nil
+	}))
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "variable",
+			value: "redis://${REDIS_HOST}:6379/0",
+			want:  "redis://cache:6379/0",
+		},
+		{
+			name:  "default_when_unset",
+			value: "redis://${MISSING_HOST:-localhost}:6379/0",
+			want:  "redis://localhost:6379/0",
+		},
+		{
+			name:  "default_when_empty",
+			value: "${EMPTY:-fallback}",
+			want:  "fallback",
+		},
+		{
+			name:  "default_not_used",
+			value: "${REDIS_HOST:-localhost}",
+			want:  "cache",
+		},
+		{
+			name:  "nested_default",
+			value: "${MISSING:-${REDIS_HOST}}",
+			want:  "cache",
+		},
+		{
+			name:  "recursive",
+			value: "postgres://app@${DB_ADDR}/app",
+			want:  "postgres://app@db:5432/app",
+		},
+		{
+			name:  "unset_without_default",
+			value: "a${MISSING}b",
+			want:  "ab",
+		},
+		{
+			name:  "variable_holding_reference",
+			value: "postgres://app:${PASSWORD}@${DB_HOST}/app",
+			want:  "postgres://app:p%40ss@db/app",
+		},
+		{
+			name:  "escaped",
+			value: "$${REDIS_HOST}",
+			want:  "${REDIS_HOST}",
+		},
+		{
+			name:    "required",
+			value:   "${MISSING:?must be set}",
+			wantErr: true,
+		},
+		{
+			name:  "required_set",
+			value: "${REDIS_HOST:?must be set}",
+			want:  "cache",
+		},
+		{
+			name:    "cycle",
+			value:   "${LOOP_A}",
+			wantErr: true,
+		},
+		{
+			name:    "self_reference",
+			value:   "${SELF}",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := &parseRun{ctx: context.Background(), secrets: map[Reference]secretResult{}}
+			got, _, err := p.interpolate(run, tt.value)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParser_ParseExpansion(t *testing.T) {
+	p := NewParser(nil, nil, WithSources(mapSource{name: "vars", values: map[string]string{
+		"REDIS_HOST": "cache",
+		"CACHE_URL":  "redis://${REDIS_HOST:-localhost}:6379/0",
+		"QUEUE_URL":  "redis://${REDIS_HOST:-localhost}:6379/1",
+	}}), WithExpansion())
+
+	got := &struct {
+		Cache Redis `config:"CACHE_URL"`
+		Queue Redis `config:"QUEUE_URL"`
+	}{}
+	require.NoError(t, p.Parse(got))
+	require.Equal(t, []string{"cache:6379"}, got.Cache.HostPort)
+	require.Equal(t, "1", got.Queue.Database)
+
+	disabled := NewParser(nil, nil, WithSources(mapSource{name: "vars", values: map[string]string{
+		"NAME": "${REDIS_HOST}",
+	}}))
+	plain := &struct {
+		Name string `config:"NAME"`
+	}{}
+	require.NoError(t, disabled.Parse(plain))
+	require.Equal(t, "${REDIS_HOST}", plain.Name)
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultNotifyDebounce = 100 * time.Millisecond
+	// kubernetesDataDir is the symlink Kubernetes swaps to update the files of a mounted Secret or ConfigMap
+	kubernetesDataDir = "..data"
+)
+
+// watchTarget is the file name in dir, or any file in dir when name is empty
+type watchTarget struct {
+	dir  string
+	name string
+}
+
+// fileSource is implemented by the sources reading files, so WatchFiles can watch them
+type fileSource interface {
+	watchTargets() []watchTarget
+}
+
+// newWatchTarget returns the target watching the file at path
+func newWatchTarget(path string) watchTarget {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	return watchTarget{dir: filepath.Dir(path), name: filepath.Base(path)}
+}
+
+// watchTargets returns the files read by the sources and the file references of run
+func (p *Parser) watchTargets(run *parseRun) []watchTarget {
+	var targets []watchTarget
+	for _, source := range p.sources {
+		if fs, ok := source.(fileSource); ok {
+			targets = append(targets, fs.watchTargets()...)
+		}
+	}
+	if _, custom := p.providers[fileScheme]; !custom {
+		for _, fr := range run.references {
+			if fr.ref.Scheme == fileScheme {
+				targets = append(targets, newWatchTarget(fr.ref.Path))
+			}
+		}
+	}
+
+	return targets
+}
+
+// WatchFiles makes the watcher reload when the files behind the dotenv, config file and secrets
+// directory sources or the file references change, including when Kubernetes swaps the "..data"
+// symlink of a mounted Secret. Events closer than debounce, 100ms by default, cause a single reload.
+// The reloads bypass the secret cache, and a copy failing to parse is not published.
+// It is only supported on Linux
+func (w *Watcher) WatchFiles(debounce time.Duration) error {
+	if debounce <= 0 {
+		debounce = defaultNotifyDebounce
+	}
+	notifier, err := newFileNotifier()
+	if err != nil {
+		return err
+	}
+
+	// a reload must not replace the targets before the notifier is published
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	w.mu.RLock()
+	watching, targets := w.notifier != nil, w.targets
+	w.mu.RUnlock()
+	if watching {
+		_ = notifier.close()
+		return nil
+	}
+	if err = notifier.watch(targets); err != nil {
+		_ = notifier.close()
+		return err
+	}
+
+	w.mu.Lock()
+	w.notifier = notifier
+	w.mu.Unlock()
+
+	w.wg.Add(1)
+	go w.notify(notifier, debounce)
+
+	return nil
+}
+
+// setTargets replaces the watched files
+func (w *Watcher) setTargets(targets []watchTarget) error {
+	w.mu.Lock()
+	w.targets = targets
+	notifier := w.notifier
+	w.mu.Unlock()
+
+	if notifier == nil {
+		return nil
+	}
+	return notifier.watch(targets)
+}
+
+func (w *Watcher) notify(notifier *fileNotifier, debounce time.Duration) {
+	defer w.wg.Done()
+	defer notifier.close() //nolint:errcheck
+
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case _, ok := <-notifier.events:
+			if !ok {
+				return
+			}
+			fire = time.After(debounce)
+		case <-fire:
+			fire = nil
+			_ = w.Reload(BypassCache(w.ctx))
+		}
+	}
+}
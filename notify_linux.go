@@ -0,0 +1,131 @@
+//go:build linux
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask selects the events of a watched directory that may change a file in it
+const inotifyMask = unix.IN_CLOSE_WRITE | unix.IN_MODIFY | unix.IN_CREATE | unix.IN_DELETE |
+	unix.IN_MOVED_TO | unix.IN_MOVED_FROM | unix.IN_ATTRIB
+
+// fileNotifier watches the directories of the targets with inotify and sends on events
+// when a watched file, or the "..data" symlink next to it, changes
+type fileNotifier struct {
+	file   *os.File
+	fd     int
+	events chan struct{}
+
+	mu    sync.Mutex
+	dirs  map[string]int
+	names map[int]map[string]bool
+}
+
+func newFileNotifier() (*fileNotifier, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("unable to init inotify: %w", err)
+	}
+
+	n := &fileNotifier{
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		fd:     fd,
+		events: make(chan struct{}, 1),
+		dirs:   map[string]int{},
+		names:  map[int]map[string]bool{},
+	}
+	go n.read()
+
+	return n, nil
+}
+
+// watch adds the targets to the watched files. Missing directories are skipped
+func (n *fileNotifier) watch(targets []watchTarget) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, target := range targets {
+		wd, ok := n.dirs[target.dir]
+		if !ok {
+			var err error
+			if wd, err = unix.InotifyAddWatch(n.fd, target.dir, inotifyMask); err != nil {
+				if errors.Is(err, unix.ENOENT) {
+					continue
+				}
+				return fmt.Errorf("unable to watch %s: %w", target.dir, err)
+			}
+			n.dirs[target.dir] = wd
+			n.names[wd] = map[string]bool{}
+		}
+
+		switch {
+		case target.name == "":
+			n.names[wd] = nil
+		case n.names[wd] != nil:
+			n.names[wd][target.name] = true
+		}
+	}
+
+	return nil
+}
+
+func (n *fileNotifier) read() {
+	defer close(n.events)
+
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		size, err := n.file.Read(buf)
+		if err != nil {
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= size; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + unix.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(event.Len)]), "\x00")
+			offset = nameStart + int(event.Len)
+
+			if n.changed(int(event.Wd), event.Mask, name) {
+				select {
+				case n.events <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// changed reports whether the event changes a watched file
+func (n *fileNotifier) changed(wd int, mask uint32, name string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	names, ok := n.names[wd]
+	if !ok {
+		return false
+	}
+	if mask&unix.IN_IGNORED != 0 {
+		// the directory is gone, it is watched again by the next reload if it comes back
+		for dir, dirWd := range n.dirs {
+			if dirWd == wd {
+				delete(n.dirs, dir)
+			}
+		}
+		delete(n.names, wd)
+		return true
+	}
+
+	return names == nil || names[name] || name == kubernetesDataDir
+}
+
+func (n *fileNotifier) close() error {
+	return n.file.Close()
+}
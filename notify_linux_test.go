@@ -0,0 +1,163 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// waitChange returns the next changes reported on changed
+func waitChange(t *testing.T, changed <-chan []Change) []Change {
+	t.Helper()
+	select {
+	case changes := <-changed:
+		return changes
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+		return nil
+	}
+}
+
+func TestWatcher_WatchFilesDotenv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte("NOTIFY_TIMEOUT=1s\n"), 0o600))
+	source, err := NewDotenvSource(path)
+	require.NoError(t, err)
+	p := NewParser(nil, nil, WithSources(source))
+
+	cfg := &struct {
+		Timeout time.Duration `config:"NOTIFY_TIMEOUT"`
+	}{}
+	w, err := p.Watch(context.Background(), cfg, 0)
+	require.NoError(t, err)
+	defer w.Stop()
+	changed := make(chan []Change, 10)
+	errs := make(chan error, 10)
+	w.OnChange(func(cfg interface{}, changes []Change) { changed <- changes })
+	w.OnError(func(err error) { errs <- err })
+	require.NoError(t, w.WatchFiles(200*time.Millisecond))
+
+	for _, timeout := range []string{"2s", "3s", "4s"} {
+		require.NoError(t, os.WriteFile(path, []byte("NOTIFY_TIMEOUT="+timeout+"\n"), 0o600))
+	}
+	require.Equal(t, []Change{{Field: "Timeout", Old: time.Second, New: 4 * time.Second}}, waitChange(t, changed))
+
+	require.NoError(t, os.WriteFile(path, []byte("NOTIFY_TIMEOUT=later\n"), 0o600))
+	select {
+	case err := <-errs:
+		require.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error reported")
+	}
+	require.Empty(t, changed)
+	require.Equal(t, 4*time.Second, w.Current().(*struct {
+		Timeout time.Duration `config:"NOTIFY_TIMEOUT"`
+	}).Timeout)
+}
+
+func TestWatcher_WatchFilesKubernetesSecret(t *testing.T) {
+	// the layout of a Secret mounted by Kubernetes: token -> ..data/token, ..data -> ..<timestamp>
+	dir := t.TempDir()
+	writeVersion := func(version, token string) {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, version), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, version, "token"), []byte(token+"\n"), 0o600))
+		require.NoError(t, os.Symlink(version, filepath.Join(dir, "..data_tmp")))
+		require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	}
+	writeVersion("..2023_01_01", "first")
+	require.NoError(t, os.Symlink(filepath.Join("..data", "token"), filepath.Join(dir, "token")))
+
+	p := NewParser(nil, nil, WithSources(mapSource{name: "test", values: map[string]string{
+		"TOKEN": "secret+file://" + filepath.Join(dir, "token"),
+	}}), WithSecretCache(NewSecretCache(time.Hour, 0)))
+	cfg := &struct {
+		Token string `config:"TOKEN"`
+	}{}
+	w, err := p.Watch(context.Background(), cfg, 0)
+	require.NoError(t, err)
+	defer w.Stop()
+	require.Equal(t, "first", cfg.Token)
+	changed := make(chan []Change, 10)
+	w.OnChange(func(cfg interface{}, changes []Change) { changed <- changes })
+	require.NoError(t, w.WatchFiles(10*time.Millisecond))
+
+	writeVersion("..2023_01_02", "second")
+	require.Equal(t, []Change{{Field: "Token", Old: "first", New: "second"}}, waitChange(t, changed))
+}
+
+func TestWatcher_WatchFilesSecretsDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "NOTIFY_PASSWORD"), []byte("old"), 0o600))
+	p := NewParser(nil, nil, WithSources(), WithSecretsDir(dir))
+
+	w, err := p.Watch(context.Background(), &struct {
+		Password string `config:"NOTIFY_PASSWORD"`
+	}{}, 0)
+	require.NoError(t, err)
+	defer w.Stop()
+	changed := make(chan []Change, 10)
+	w.OnChange(func(cfg interface{}, changes []Change) { changed <- changes })
+	require.NoError(t, w.WatchFiles(10*time.Millisecond))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "NOTIFY_PASSWORD"), []byte("new"), 0o600))
+	require.Equal(t, []Change{{Field: "Password", Old: "old", New: "new"}}, waitChange(t, changed))
+}
+
+func TestWatcher_WatchFilesRetry(t *testing.T) {
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "secrets")
+	require.NoError(t, os.WriteFile(blocker, nil, 0o600))
+	p := NewParser(nil, nil, WithSecretsDir(filepath.Join(blocker, "app")))
+
+	cfg := &struct {
+		Token string `config:"NOTIFY_RETRY_TOKEN"`
+	}{}
+	w, err := p.Watch(context.Background(), cfg, 0)
+	require.NoError(t, err)
+	defer w.Stop()
+	changed := make(chan []Change, 10)
+	w.OnChange(func(cfg interface{}, changes []Change) { changed <- changes })
+	require.Error(t, w.WatchFiles(50*time.Millisecond))
+
+	require.NoError(t, os.Remove(blocker))
+	require.NoError(t, os.MkdirAll(filepath.Join(blocker, "app"), 0o700))
+	require.NoError(t, w.WatchFiles(50*time.Millisecond))
+	require.NoError(t, os.WriteFile(filepath.Join(blocker, "app", "NOTIFY_RETRY_TOKEN"), []byte("token"), 0o600))
+	require.Equal(t, []Change{{Field: "Token", Old: "", New: "token"}}, waitChange(t, changed))
+}
+
+func TestWatcher_ReloadWatchError(t *testing.T) {
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "secrets")
+	require.NoError(t, os.WriteFile(blocker, nil, 0o600))
+	source := &syncSource{values: map[string]string{"NOTIFY_NAME": "first"}}
+	p := NewParser(nil, nil, WithSources(source), WithSecretsDir(filepath.Join(blocker, "app")))
+
+	cfg := &struct {
+		Name string `config:"NOTIFY_NAME"`
+	}{}
+	w, err := p.Watch(context.Background(), cfg, 0)
+	require.NoError(t, err)
+	defer w.Stop()
+	notifier, err := newFileNotifier()
+	require.NoError(t, err)
+	defer notifier.close() //nolint:errcheck
+	w.notifier = notifier
+
+	var changes []Change
+	var errs []error
+	w.OnChange(func(cfg interface{}, c []Change) { changes = c })
+	w.OnError(func(err error) { errs = append(errs, err) })
+
+	source.set("NOTIFY_NAME", "second")
+	require.Error(t, w.Reload(context.Background()))
+	require.Equal(t, []Change{{Field: "Name", Old: "first", New: "second"}}, changes)
+	require.Len(t, errs, 1)
+	require.Equal(t, "second", w.Current().(*struct {
+		Name string `config:"NOTIFY_NAME"`
+	}).Name)
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package config
+
+import "fmt"
+
+// fileNotifier is not supported on this platform
+type fileNotifier struct {
+	events chan struct{}
+}
+
+func newFileNotifier() (*fileNotifier, error) {
+	return nil, fmt.Errorf("file notifications are only supported on linux")
+}
+
+func (n *fileNotifier) watch([]watchTarget) error {
+	return nil
+}
+
+func (n *fileNotifier) close() error {
+	return nil
+}
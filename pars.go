@@ -3,25 +3,68 @@ package config
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/url"
-	"os"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
-	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/ssm"
 )
 
-// JWT object for parsing JWT
+// JWT object for parsing JWT. It is parsed from a JSON list of SecretVersion, newest first, e.g. the one
+// of the reference "gcp:projects/p/secrets/jwt?versions=2", or else from "<access key>,<refresh key>".
+// The newest version is the current key, the older ones are kept in PreviousKeys
 type JWT struct {
 	SigningKeyAT string
 	SigningKeyRT string
+	// KeyID identifies the current key, e.g. in the "kid" header of the signed tokens
+	KeyID       string
+	ActivatedAt time.Time
+	// PreviousKeys still verify the tokens signed before the rotation, newest first
+	PreviousKeys []JWTKey
+}
+
+// JWTKey object for a JWT signing key
+type JWTKey struct {
+	ID           string
+	SigningKeyAT string
+	SigningKeyRT string
+	ActivatedAt  time.Time
+}
+
+// Current returns the current key
+func (j *JWT) Current() JWTKey {
+	return JWTKey{ID: j.KeyID, SigningKeyAT: j.SigningKeyAT, SigningKeyRT: j.SigningKeyRT, ActivatedAt: j.ActivatedAt}
+}
+
+// Key returns the current or previous key with the given ID, to verify a token signed with it
+func (j *JWT) Key(id string) (JWTKey, bool) {
+	if current := j.Current(); current.ID == id {
+		return current, true
+	}
+	for _, key := range j.PreviousKeys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+
+	return JWTKey{}, false
+}
+
+// newJWTKey parses "<access key>,<refresh key>" or a single key used for both
+func newJWTKey(v string) JWTKey {
+	arr := strings.Split(v, ",")
+	key := JWTKey{SigningKeyAT: arr[0], SigningKeyRT: arr[0]}
+	if len(arr) == 2 {
+		key.SigningKeyRT = arr[1]
+	}
+
+	return key
 }
 
 // Postgres object for parsing Postgres connection URL
@@ -275,7 +318,7 @@ func defaultTypeParsers() map[reflect.Type]ParserFunc { //nolint: gocognit,gocri
 				obj.ReplicaSet = v[0]
 			}
 
-			if v, ok := queryParams["authSourcwaht waht waht e"]; ok {
+			if v, ok := queryParams["authSource"]; ok {
 				obj.AuthSource = v[0]
 			}
 
@@ -317,13 +360,26 @@ func defaultTypeParsers() map[reflect.Type]ParserFunc { //nolint: gocognit,gocri
 			return obj, nil
 		},
 		reflect.TypeOf(JWT{}): func(v string) (interface{}, error) { //nolint: dupl,gocritic
-			obj := JWT{}
-			arr := strings.Split(v, ",")
-			obj.SigningKeyAT = arr[0]
-			obj.SigningKeyRT = arr[0]
+			var versions []SecretVersion
+			if err := json.Unmarshal([]byte(v), &versions); err != nil || len(versions) == 0 {
+				key := newJWTKey(v)
+				return JWT{SigningKeyAT: key.SigningKeyAT, SigningKeyRT: key.SigningKeyRT}, nil
+			}
+			keys := make([]JWTKey, len(versions))
+			for i, version := range versions {
+				keys[i] = newJWTKey(version.Value)
+				keys[i].ID = version.ID
+				keys[i].ActivatedAt = version.CreatedAt
+			}
 
-			if len(arr) == 2 {
-				obj.SigningKeyRT = arr[1]
+			obj := JWT{
+				SigningKeyAT: keys[0].SigningKeyAT,
+				SigningKeyRT: keys[0].SigningKeyRT,
+				KeyID:        keys[0].ID,
+				ActivatedAt:  keys[0].ActivatedAt,
+			}
+			if len(keys) > 1 {
+				obj.PreviousKeys = keys[1:]
 			}
 
 			return obj, nil
@@ -350,16 +406,47 @@ type ParserFunc func(v string) (interface{}, error)
 
 // Parser object for parsing that holds connection information
 type Parser struct {
-	GCP *secretmanager.Client
-	AWS *session.Session
+	GCP   *secretmanager.Client
+	AWS   *session.Session
+	Vault *VaultClient
+	Azure *AzureKeyVaultClient
+
+	providers map[string]SecretProvider
+	sources   []Source
+	flags     map[string]*flagValue
+	origins   atomic.Value
+
+	strictReferences bool
+	expandVars       bool
+	parallelism      int
+	timeout          time.Duration
+	retry            RetryPolicy
+	cache            *SecretCache
+}
+
+// Option configures a Parser
+type Option func(p *Parser)
+
+// WithSecretsDir makes the parser look up keys missing in the previous sources
+// in dir, which holds one file per key, e.g. "/run/secrets"
+func WithSecretsDir(dir string) Option {
+	return func(p *Parser) {
+		p.sources = append(p.sources, DirSource{Dir: dir})
+	}
 }
 
 // NewParser creates a new Parser
-func NewParser(GCP *secretmanager.Client, AWS *session.Session) Parser {
-	return Parser{
-		GCP: GCP,
-		AWS: AWS,
+func NewParser(GCP *secretmanager.Client, AWS *session.Session, opts ...Option) Parser {
+	p := Parser{
+		GCP:     GCP,
+		AWS:     AWS,
+		sources: []Source{EnvSource{}},
+	}
+	for _, opt := range opts {
+		opt(&p)
 	}
+
+	return p
 }
 
 // Parse parse configuration
@@ -369,30 +456,83 @@ func (p *Parser) Parse(v interface{}) error {
 
 // ParseWithFuncs parses configuration from environment variables with ParserFunc
 func (p *Parser) ParseWithFuncs(v interface{}, funcMap map[reflect.Type]ParserFunc) error {
+	return p.ParseWithFuncsContext(context.Background(), v, funcMap)
+}
+
+// ParseWithFuncsContext is ParseWithFuncs with a context passed to every provider call
+func (p *Parser) ParseWithFuncsContext(ctx context.Context, v interface{}, funcMap map[reflect.Type]ParserFunc) error {
+	_, err := p.parse(ctx, v, funcMap)
+	return err
+}
+
+// parse parses v and returns the state of the run
+func (p *Parser) parse(ctx context.Context, v interface{}, funcMap map[reflect.Type]ParserFunc) (*parseRun, error) {
 	ptrRef := reflect.ValueOf(v)
 	if ptrRef.Kind() != reflect.Ptr {
-		return fmt.Errorf("presented object is not a pointer")
+		return nil, fmt.Errorf("presented object is not a pointer")
 	}
 	ref := ptrRef.Elem()
 	if ref.Kind() != reflect.Struct {
-		return fmt.Errorf("presented object %v is not a struct ", ref.Kind())
+		return nil, fmt.Errorf("presented object %v is not a struct ", ref.Kind())
+	}
+	run := &parseRun{
+		ctx:     ctx,
+		parsers: defaultTypeParsers(),
+		origins: map[string]Origin{},
+		secrets: map[Reference]secretResult{},
 	}
-	parsers := defaultTypeParsers()
 	for k, v := range funcMap {
-		parsers[k] = v
+		run.parsers[k] = v
 	}
+	if err := p.loadSources(); err != nil {
+		return nil, err
+	}
+
+	p.prefetch(ref, run)
+	if err := p.parseConfig(ref, run, "", ""); err != nil {
+		if ctx.Err() != nil {
+			return nil, &PendingError{Err: ctx.Err(), Fields: run.pendingFields()}
+		}
+		return nil, err
+	}
+	p.origins.Store(run.origins)
+
+	return run, nil
+}
+
+// parseRun holds the state of a single Parse call
+type parseRun struct {
+	ctx     context.Context
+	parsers map[reflect.Type]ParserFunc
+	origins map[string]Origin
+	// secrets holds the payloads fetched during the call, so every secret is fetched once
+	secrets map[Reference]secretResult
+	// collecting makes resolve record the references in collected instead of fetching them
+	collecting bool
+	collected  []Reference
+	// field is the field being collected, references links the collected fields to their secrets
+	field      string
+	references []fieldReference
+	// scope is the source of the path reference the fields are read from, nil otherwise
+	scope Source
+}
 
-	return p.parseConfig(ref, parsers)
+// secretResult is the outcome of fetching a secret payload
+type secretResult struct {
+	value string
+	err   error
 }
 
-func (p *Parser) parseConfig(ref reflect.Value, funcMap map[reflect.Type]ParserFunc) (err error) {
+// parseConfig parses the fields of ref. prefix holds the dotted keys and path the
+// dotted field names of the enclosing struct fields
+func (p *Parser) parseConfig(ref reflect.Value, run *parseRun, prefix, path string) (err error) {
 	refType := ref.Type()
 
 	for i := 0; i < refType.NumField(); i++ {
 		refField := ref.Field(i)
 		refTypeField := refType.Field(i)
 
-		if err = p.doParseField(refField, refTypeField, funcMap); err != nil {
+		if err = p.doParseField(refField, refTypeField, run, prefix, joinKey(path, refTypeField.Name)); err != nil {
 			return fmt.Errorf("error parsing field %w", err)
 		}
 	}
@@ -400,13 +540,17 @@ func (p *Parser) parseConfig(ref reflect.Value, funcMap map[reflect.Type]ParserF
 	return err
 }
 
-func (p *Parser) doParseField(refField reflect.Value, refTypeField reflect.StructField, funcMap map[reflect.Type]ParserFunc) error { //nolint: gocritic
+func (p *Parser) doParseField(refField reflect.Value, refTypeField reflect.StructField, run *parseRun, prefix, path string) error { //nolint: gocritic
 	if !refField.CanSet() {
 		return fmt.Errorf("field can not be set")
 	}
 
 	tags := strings.Split(refTypeField.Tag.Get("config"), ",")
-	value, err := p.parseRow(tags[0])
+	if ok, err := p.parsePath(refField, refTypeField, run, prefix, tags[0], path); ok || err != nil {
+		return err
+	}
+
+	value, origin, err := p.parseRow(run, prefix, tags[0])
 	if err != nil {
 		return fmt.Errorf("while parsing row %v error %w", value, err)
 	}
@@ -429,11 +573,13 @@ func (p *Parser) doParseField(refField reflect.Value, refTypeField reflect.Struc
 	}
 
 	if value != "" {
-		return set(refField, refTypeField, value, defaultTypeParsers())
+		origin.Field = path
+		run.origins[path] = origin
+		return set(refField, refTypeField, value, run.parsers)
 	}
 
-	if reflect.Struct == refField.Kind() {
-		return p.parseConfig(refField, funcMap)
+	if _, hasParser := run.parsers[refTypeField.Type]; reflect.Struct == refField.Kind() && !hasParser {
+		return p.parseConfig(refField, run, joinKey(prefix, tags[0]), path)
 	}
 
 	return nil
@@ -469,52 +615,87 @@ func set(field reflect.Value, sf reflect.StructField, value string, funcMap map[
 		return nil
 	}
 
-	return nil
-}
-
-func (p *Parser) parseRow(value string) (string, error) {
-	value = os.Getenv(value)
-	arrVal := strings.Split(value, ":")
-	switch arrVal[0] {
-	case "aws":
-		return p.getFromAWS(arrVal[1])
-	case "gcp":
-		return p.getFromGCP(arrVal[1])
+	if typee.Kind() == reflect.Slice {
+		return setSlice(fieldee, typee, value, funcMap)
 	}
 
-	return value, nil
+	return nil
 }
 
-func (p *Parser) getFromAWS(key string) (string, error) {
-	if p.AWS == nil {
-		return key, fmt.Errorf("aws connection is not set")
+// setSlice parses a comma separated value into a slice field
+func setSlice(field reflect.Value, typee reflect.Type, value string, funcMap map[reflect.Type]ParserFunc) error {
+	elemType := typee.Elem()
+	parserFunc, ok := funcMap[elemType]
+	if !ok {
+		parserFunc, ok = defaultBuiltInParsers[elemType.Kind()]
 	}
-
-	input := &ssm.GetParameterInput{
-		Name:           &key,
-		WithDecryption: aws.Bool(true),
+	if !ok {
+		return nil
 	}
 
-	output, err := ssm.New(p.AWS).GetParameter(input)
-	if err != nil || output == nil {
-		return "", fmt.Errorf("err while get aws secret: %v", err)
+	parts := strings.Split(value, ",")
+	result := reflect.MakeSlice(typee, 0, len(parts))
+	for _, part := range parts {
+		val, err := parserFunc(part)
+		if err != nil {
+			return fmt.Errorf("error parsing value %q for field %v: %v", part, field, err)
+		}
+		result = reflect.Append(result, reflect.ValueOf(val).Convert(elemType))
 	}
 
-	return output.String(), nil
+	field.Set(result)
+	return nil
 }
 
-func (p *Parser) getFromGCP(key string) (string, error) {
-	if p.GCP == nil {
-		return key, fmt.Errorf("gcp connection is not set")
+// parseRow looks up key and resolves the value when it is a secret reference.
+// It returns the origin of the value without the field set
+func (p *Parser) parseRow(run *parseRun, prefix, key string) (string, Origin, error) {
+	value, foundKey, source, _ := p.lookupRun(run, prefix, key)
+	origin := Origin{Key: foundKey, Source: source}
+
+	ref, literal, ok, err := p.parseReference(value)
+	if err != nil {
+		return "", origin, err
+	}
+	if !ok {
+		if literal != value {
+			return literal, origin, nil
+		}
+		interpolated, found, err := p.interpolate(run, value)
+		if found {
+			origin.Reference = value
+		}
+		return interpolated, origin, err
 	}
 
-	req := &secretmanagerpb.GetSecretRequest{
-		Name: key}
+	origin.Reference = value
+	resolved, err := p.resolve(run, ref)
 
-	output, err := p.GCP.GetSecret(context.Background(), req)
-	if err != nil {
-		return "", fmt.Errorf("err while get gcp secret %w", err)
+	return resolved, origin, err
+}
+
+// resolve fetches the secret ref points to, once per Parse call, and applies the selector
+func (p *Parser) resolve(run *parseRun, ref Reference) (string, error) {
+	result, ok := run.secrets[ref.secret()]
+	if run.collecting {
+		run.references = append(run.references, fieldReference{field: run.field, ref: ref.secret()})
+		if !ok {
+			run.secrets[ref.secret()] = result
+			run.collected = append(run.collected, ref.secret())
+		}
+		return "", nil
+	}
+	if !ok {
+		if result, ok = p.cachedSecret(run, ref); !ok {
+			provider, _ := p.provider(ref.Scheme)
+			result.value, result.err = p.resolveKey(run.ctx, provider, ref)
+			p.cacheSecret(ref, result)
+		}
+		run.secrets[ref.secret()] = result
+	}
+	if result.err != nil || ref.Selector == "" {
+		return result.value, result.err
 	}
 
-	return output.String(), nil
+	return selectValue(result.value, ref.Selector)
 }
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// pathSource looks up values among the secrets resolved from a path reference
+type pathSource struct {
+	name   string
+	values map[string]string
+}
+
+// Lookup returns the secret with the relative name key
+func (s pathSource) Lookup(key string) (string, bool) {
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// Name returns the path reference
+func (s pathSource) Name() string {
+	return s.name
+}
+
+// parsePath fills a struct or map field from a path reference such as "aws:/service/prod/".
+// ok reports whether the field holds a path reference
+func (p *Parser) parsePath(field reflect.Value, sf reflect.StructField, run *parseRun, prefix, key, path string) (ok bool, err error) { //nolint: gocritic
+	value, foundKey, source, _ := p.lookupRun(run, prefix, key)
+	provider, ref, ok := p.pathReference(value, sf.Type, run.parsers)
+	if !ok {
+		return false, nil
+	}
+
+	values, err := callProvider(run.ctx, p, value, func(ctx context.Context) (map[string]string, error) {
+		return provider.ResolvePath(ctx, ref.key())
+	})
+	if err != nil {
+		return true, fmt.Errorf("while resolving %s: %w", value, err)
+	}
+
+	if sf.Type.Kind() == reflect.Map {
+		run.origins[path] = Origin{Field: path, Key: foundKey, Source: source, Reference: value}
+		return true, setMap(field, sf.Type, values, run.parsers)
+	}
+
+	scoped := *run
+	scoped.scope = pathSource{name: value, values: values}
+	return true, p.parseConfig(field, &scoped, "", path)
+}
+
+// pathReference returns the provider and the reference when value is a path reference,
+// i.e. a reference ending with "/" to a PathSecretProvider, and typ is a struct or a map
+func (p *Parser) pathReference(value string, typ reflect.Type, parsers map[reflect.Type]ParserFunc) (PathSecretProvider, Reference, bool) {
+	if value == "" {
+		return nil, Reference{}, false
+	}
+	if _, ok := parsers[typ]; ok {
+		return nil, Reference{}, false
+	}
+	if typ.Kind() != reflect.Struct && (typ.Kind() != reflect.Map || typ.Key().Kind() != reflect.String) {
+		return nil, Reference{}, false
+	}
+
+	ref, _, ok, err := p.parseReference(value)
+	if err != nil || !ok || ref.Selector != "" || !strings.HasSuffix(ref.Path, "/") {
+		return nil, Reference{}, false
+	}
+	provider, _ := p.provider(ref.Scheme)
+	pathProvider, ok := provider.(PathSecretProvider)
+
+	return pathProvider, ref, ok
+}
+
+// setMap sets a map field from the secrets resolved from a path reference
+func setMap(field reflect.Value, typee reflect.Type, values map[string]string, funcMap map[reflect.Type]ParserFunc) error {
+	elemType := typee.Elem()
+	parserFunc, ok := funcMap[elemType]
+	if !ok {
+		parserFunc, ok = defaultBuiltInParsers[elemType.Kind()]
+	}
+	if !ok {
+		return fmt.Errorf("map of %v is not supported", elemType)
+	}
+
+	result := reflect.MakeMapWithSize(typee, len(values))
+	for key, value := range values {
+		val, err := parserFunc(value)
+		if err != nil {
+			return fmt.Errorf("error parsing value of %q for field %v: %v", key, field, err)
+		}
+		result.SetMapIndex(reflect.ValueOf(key).Convert(typee.Key()), reflect.ValueOf(val).Convert(elemType))
+	}
+
+	field.Set(result)
+	return nil
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// defaultParallelism is the number of secrets fetched at the same time by default
+const defaultParallelism = 8
+
+// WithParallelism sets the number of secrets fetched at the same time. Values below 1
+// make the parser fetch the secrets one by one, for providers that are not safe for concurrent use
+func WithParallelism(n int) Option {
+	return func(p *Parser) {
+		if n < 1 {
+			n = 1
+		}
+		p.parallelism = n
+	}
+}
+
+// prefetch fetches the secrets referenced by the fields of ref concurrently and stores them
+// in run.secrets, so assigning the fields afterwards does not wait for one round-trip at a time.
+// Errors are stored with the results and reported by the field that needs the secret
+func (p *Parser) prefetch(ref reflect.Value, run *parseRun) {
+	collect := &parseRun{parsers: run.parsers, secrets: map[Reference]secretResult{}, collecting: true}
+	p.collectReferences(ref.Type(), collect, "", "")
+	run.references = collect.references
+	var refs []Reference
+	for _, ref := range collect.collected {
+		if result, ok := p.cachedSecret(run, ref); ok {
+			run.secrets[ref] = result
+			continue
+		}
+		refs = append(refs, ref)
+	}
+	if len(refs) == 0 {
+		return
+	}
+
+	parallelism := p.parallelism
+	if parallelism == 0 {
+		parallelism = defaultParallelism
+	}
+	// references of batch providers are fetched together, the others one by one
+	var jobs [][]int
+	batches := map[string]int{}
+	for i, ref := range refs {
+		provider, _ := p.provider(ref.Scheme)
+		if _, ok := provider.(BatchSecretProvider); ok {
+			if j, ok := batches[ref.Scheme]; ok {
+				jobs[j] = append(jobs[j], i)
+				continue
+			}
+			batches[ref.Scheme] = len(jobs)
+		}
+		jobs = append(jobs, []int{i})
+	}
+
+	results := make([]secretResult, len(refs))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		select {
+		case sem <- struct{}{}:
+		case <-run.ctx.Done():
+			for _, i := range job {
+				results[i].err = run.ctx.Err()
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(job []int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			p.fetch(run.ctx, refs, job, results)
+		}(job)
+	}
+	wg.Wait()
+
+	for i, ref := range refs {
+		run.secrets[ref] = results[i]
+		p.cacheSecret(ref, results[i])
+	}
+}
+
+// fetch resolves refs[i] into results[i] for every i in job, which holds references of one scheme
+func (p *Parser) fetch(ctx context.Context, refs []Reference, job []int, results []secretResult) {
+	provider, _ := p.provider(refs[job[0]].Scheme)
+	batch, ok := provider.(BatchSecretProvider)
+	if !ok || len(job) == 1 {
+		for _, i := range job {
+			results[i].value, results[i].err = p.resolveKey(ctx, provider, refs[i])
+		}
+		return
+	}
+
+	keys := make([]string, len(job))
+	names := make([]string, len(job))
+	for n, i := range job {
+		keys[n] = refs[i].key()
+		names[n] = refs[i].String()
+	}
+	values, err := callProvider(ctx, p, strings.Join(names, ","), func(ctx context.Context) (map[string]string, error) {
+		return batch.ResolveBatch(ctx, keys)
+	})
+	for _, i := range job {
+		if err != nil {
+			results[i].err = err
+			continue
+		}
+		if value, ok := values[refs[i].key()]; ok {
+			results[i].value = value
+			continue
+		}
+		results[i].value, results[i].err = p.resolveKey(ctx, provider, refs[i])
+	}
+}
+
+// collectReferences adds the secrets referenced by the fields of refType to run.collected
+// in field order. It follows the lookups of parseConfig without fetching anything
+func (p *Parser) collectReferences(refType reflect.Type, run *parseRun, prefix, path string) {
+	for i := 0; i < refType.NumField(); i++ {
+		sf := refType.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key := strings.Split(sf.Tag.Get("config"), ",")[0]
+
+		if value, _, _, _ := p.lookup(prefix, key); value != "" {
+			if _, _, ok := p.pathReference(value, sf.Type, run.parsers); !ok {
+				run.field = joinKey(path, sf.Name)
+				_, _, _ = p.parseRow(run, prefix, key)
+			}
+			continue
+		}
+		if _, hasParser := run.parsers[sf.Type]; sf.Type.Kind() == reflect.Struct && !hasParser {
+			p.collectReferences(sf.Type, run, joinKey(prefix, key), joinKey(path, sf.Name))
+		}
+	}
+}
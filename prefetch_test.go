@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// slowProvider counts the calls and the concurrent calls to Resolve
+type slowProvider struct {
+	mu       sync.Mutex
+	calls    map[string]int
+	inFlight int32
+	maxSeen  int32
+}
+
+func (s *slowProvider) Resolve(key string) (string, error) {
+	n := atomic.AddInt32(&s.inFlight, 1)
+	defer atomic.AddInt32(&s.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&s.maxSeen)
+		if n <= max || atomic.CompareAndSwapInt32(&s.maxSeen, max, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	s.mu.Lock()
+	s.calls[key]++
+	s.mu.Unlock()
+	if key == "missing" || key == "broken" {
+		return "", fmt.Errorf("secret %s not found", key)
+	}
+	return "value-" + key, nil
+}
+
+func TestParser_ParseConcurrently(t *testing.T) {
+	values := map[string]string{
+		"NESTED.NAME": "${slow:e}",
+		"URL":         "postgres://app:${slow:b}@db/app",
+	}
+	for i, key := range []string{"A", "B", "C", "D", "E"} {
+		values[key] = fmt.Sprintf("slow:%c", 'a'+i)
+	}
+
+	tests := []struct {
+		name         string
+		parallelism  int
+		wantMaxSeen  int32
+		wantMinSeen  int32
+		extra        map[string]string
+		wantErrField string
+	}{
+		{
+			name:        "bounded",
+			parallelism: 2,
+			wantMinSeen: 2,
+			wantMaxSeen: 2,
+		},
+		{
+			name:        "sequential",
+			parallelism: 0,
+			wantMinSeen: 1,
+			wantMaxSeen: 1,
+		},
+		{
+			name:         "first_error_in_field_order",
+			parallelism:  4,
+			wantMinSeen:  2,
+			wantMaxSeen:  4,
+			extra:        map[string]string{"B": "slow:missing", "D": "slow:broken"},
+			wantErrField: "missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := mapSource{name: "test", values: map[string]string{}}
+			for k, v := range values {
+				source.values[k] = v
+			}
+			for k, v := range tt.extra {
+				source.values[k] = v
+			}
+			provider := &slowProvider{calls: map[string]int{}}
+			p := NewParser(nil, nil, WithSources(source), WithParallelism(tt.parallelism))
+			p.RegisterProvider("slow", provider)
+
+			got := &struct {
+				A      string `config:"A"`
+				B      string `config:"B"`
+				C      string `config:"C"`
+				D      string `config:"D"`
+				E      string `config:"E"`
+				URL    string `config:"URL"`
+				Nested struct {
+					Name string `config:"NAME"`
+				} `config:"NESTED"`
+			}{}
+			err := p.Parse(got)
+			if tt.wantErrField != "" {
+				require.ErrorContains(t, err, tt.wantErrField)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "value-a", got.A)
+			require.Equal(t, "value-e", got.E)
+			require.Equal(t, "postgres://app:value-b@db/app", got.URL)
+			require.Equal(t, "value-e", got.Nested.Name)
+			require.LessOrEqual(t, provider.maxSeen, tt.wantMaxSeen)
+			require.GreaterOrEqual(t, provider.maxSeen, tt.wantMinSeen)
+			for key, calls := range provider.calls {
+				require.Equal(t, 1, calls, key)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"context"
+	"time"
+)
+
+// SecretProvider resolves a secret reference to its value. The parser fetches the
+// secrets of a struct concurrently, so Resolve must be safe for concurrent use
+// unless the parser is created with WithParallelism(1)
+type SecretProvider interface {
+	Resolve(ref string) (string, error)
+}
+
+// SecretProviderFunc allows using an ordinary function as a SecretProvider
+type SecretProviderFunc func(ref string) (string, error)
+
+// Resolve calls f(ref)
+func (f SecretProviderFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+// BatchSecretProvider is a SecretProvider that can resolve several references in one call.
+// The parser uses it when a struct references more than one secret of its scheme
+type BatchSecretProvider interface {
+	SecretProvider
+	// ResolveBatch returns the values of the found references by reference.
+	// The references missing from the result are resolved one by one
+	ResolveBatch(ctx context.Context, refs []string) (map[string]string, error)
+}
+
+// PathSecretProvider is a SecretProvider that can resolve all the secrets under a path.
+// A reference ending with "/" in a struct or map field is resolved with ResolvePath
+type PathSecretProvider interface {
+	SecretProvider
+	// ResolvePath returns the secrets under path by their dotted relative names,
+	// e.g. "db.password" for "/service/prod/db/password" under "/service/prod/"
+	ResolvePath(ctx context.Context, path string) (map[string]string, error)
+}
+
+// SecretVersion is a version of a secret
+type SecretVersion struct {
+	ID        string    `json:"id"`
+	Value     string    `json:"value"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// VersionedSecretProvider is a SecretProvider that can resolve the latest versions of a secret.
+// A reference with the "versions=<n>" query, e.g. "gcp:projects/p/secrets/jwt?versions=2",
+// resolves to the n latest versions encoded as a JSON list of SecretVersion, newest first
+type VersionedSecretProvider interface {
+	SecretProvider
+	ResolveVersions(ctx context.Context, ref string, n int) ([]SecretVersion, error)
+}
+
+// RegisterProvider registers provider for values prefixed with "scheme:".
+// Registered providers take precedence over the built-in ones. provider is called
+// from several goroutines at once, see SecretProvider
+func (p *Parser) RegisterProvider(scheme string, provider SecretProvider) {
+	if p.providers == nil {
+		p.providers = map[string]SecretProvider{}
+	}
+	p.providers[scheme] = provider
+}
+
+// provider returns the provider registered for scheme, falling back to the
+// built-in providers backed by the Parser connections
+func (p *Parser) provider(scheme string) (SecretProvider, bool) {
+	if provider, ok := p.providers[scheme]; ok {
+		return provider, true
+	}
+
+	switch scheme {
+	case "aws":
+		return &SSMProvider{Session: p.AWS}, true
+	case "asm":
+		return &ASMProvider{Session: p.AWS}, true
+	case "gcp":
+		return &GCPProvider{Client: p.GCP}, true
+	case "vault":
+		return &VaultProvider{Client: p.Vault}, true
+	case "azkv":
+		return &AzureKeyVaultProvider{Client: p.Azure}, true
+	case fileScheme:
+		return FileProvider{}, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParser_RegisterProvider(t *testing.T) {
+	tests := []struct {
+		name      string
+		provider  SecretProvider
+		args      interface{}
+		wantValue interface{}
+		wantErr   bool
+		testData  string
+		testKey   string
+	}{
+		{
+			name: "custom_scheme",
+			provider: SecretProviderFunc(func(ref string) (string, error) {
+				return "resolved-" + ref, nil
+			}),
+			args: &struct {
+				TestStr string `config:"provider_string"`
+			}{},
+			wantValue: &struct {
+				TestStr string `config:"provider_string"`
+			}{TestStr: "resolved-key"},
+			testData: "custom:key",
+			testKey:  "provider_string",
+		},
+		{
+			name: "custom_scheme_error",
+			provider: SecretProviderFunc(func(ref string) (string, error) {
+				return "", fmt.Errorf("not found %s", ref)
+			}),
+			args: &struct {
+				TestStr string `config:"provider_string"`
+			}{},
+			wantErr:  true,
+			testData: "custom:key",
+			testKey:  "provider_string",
+		},
+		{
+			name: "unknown_scheme",
+			provider: SecretProviderFunc(func(ref string) (string, error) {
+				return "resolved-" + ref, nil
+			}),
+			args: &struct {
+				TestStr string `config:"provider_string"`
+			}{},
+			wantValue: &struct {
+				TestStr string `config:"provider_string"`
+			}{TestStr: "other:key"},
+			testData: "other:key",
+			testKey:  "provider_string",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := os.Setenv(tt.testKey, tt.testData)
+			require.NoError(t, err)
+			p := &Parser{}
+			p.RegisterProvider("custom", tt.provider)
+			gotErr := p.Parse(tt.args)
+			if tt.wantErr {
+				require.Error(t, gotErr, "")
+			} else {
+				require.NoError(t, gotErr)
+				require.EqualValues(t, tt.wantValue, tt.args)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	referencePrefix    = "secret+"
+	referenceSeparator = "://"
+	referenceEscape    = `\`
+	// versionsParam is the query parameter asking for the latest versions of a secret
+	versionsParam = "versions"
+)
+
+// Reference is a secret reference found in a value. It is written either as
+//
+//	secret+<scheme>://<path>[?<query>][#<selector>]
+//
+// e.g. "secret+gcp://projects/p/secrets/s?version=3", or in the short form "<scheme>:<path>",
+// e.g. "gcp:projects/p/secrets/s". The short form is recognized for the built-in schemes "aws", "asm",
+// "gcp", "vault", "azkv" and "file" and for the registered ones, unless the parser is created with
+// WithStrictReferences. The selector picks a single value from a JSON or YAML payload, e.g. "gcp:db-creds#password".
+// A value that would be read as a reference is kept literally when prefixed with a backslash,
+// e.g. `\aws:not-a-reference`
+type Reference struct {
+	Scheme string
+	// Path identifies the secret for the provider
+	Path string
+	// RawQuery holds the provider specific options, without the '?'
+	RawQuery string
+	// Selector of a value in the payload, without the '#'
+	Selector string
+}
+
+// String returns the reference in the "secret+" form
+func (r Reference) String() string {
+	if r.Selector == "" {
+		return referencePrefix + r.Scheme + referenceSeparator + r.key()
+	}
+	return referencePrefix + r.Scheme + referenceSeparator + r.key() + "#" + r.Selector
+}
+
+// secret returns the reference without the selector, identifying the fetched payload
+func (r Reference) secret() Reference {
+	r.Selector = ""
+	return r
+}
+
+// key returns the part of the reference passed to the provider
+func (r Reference) key() string {
+	if r.RawQuery == "" {
+		return r.Path
+	}
+	return r.Path + "?" + r.RawQuery
+}
+
+// versions returns the number of versions asked for by the "versions" query parameter
+// and the reference without it. ok is false when the parameter is not set
+func (r Reference) versions() (n int, rest Reference, ok bool, err error) {
+	query, err := url.ParseQuery(r.RawQuery)
+	if err != nil || !query.Has(versionsParam) {
+		return 0, r, false, nil
+	}
+	n, err = strconv.Atoi(query.Get(versionsParam))
+	if err != nil || n < 1 {
+		return 0, r, true, fmt.Errorf("invalid number of versions %q in %s", query.Get(versionsParam), r)
+	}
+	query.Del(versionsParam)
+	r.RawQuery = query.Encode()
+
+	return n, r, true, nil
+}
+
+// parseReference parses value. When value is not a reference, ok is false and
+// literal holds the value with a leading escape removed
+func (p *Parser) parseReference(value string) (ref Reference, literal string, ok bool, err error) {
+	if unescaped := strings.TrimPrefix(value, referenceEscape); unescaped != value {
+		if p.isReference(unescaped) {
+			return Reference{}, unescaped, false, nil
+		}
+		return Reference{}, value, false, nil
+	}
+	if !p.isReference(value) {
+		return Reference{}, value, false, nil
+	}
+
+	var scheme, rest string
+	if strings.HasPrefix(value, referencePrefix) {
+		var found bool
+		scheme, rest, found = strings.Cut(strings.TrimPrefix(value, referencePrefix), referenceSeparator)
+		if !found {
+			return Reference{}, "", false, fmt.Errorf("invalid secret reference %q: expected %s<scheme>%s<path>", value, referencePrefix, referenceSeparator)
+		}
+		if _, known := p.provider(scheme); !known {
+			return Reference{}, "", false, fmt.Errorf("invalid secret reference %q: unknown scheme %q", value, scheme)
+		}
+	} else {
+		scheme, rest, _ = strings.Cut(value, ":")
+	}
+
+	ref, err = newReference(scheme, rest)
+	if err != nil {
+		return Reference{}, "", false, fmt.Errorf("invalid secret reference %q: %w", value, err)
+	}
+
+	return ref, "", true, nil
+}
+
+// isReference reports whether value is written as a reference
+func (p *Parser) isReference(value string) bool {
+	if strings.HasPrefix(value, referencePrefix) {
+		return true
+	}
+	if p.strictReferences {
+		return false
+	}
+	scheme, _, found := strings.Cut(value, ":")
+	if !found {
+		return false
+	}
+	_, known := p.provider(scheme)
+
+	return known
+}
+
+// newReference splits rest into the path, the query and the selector
+func newReference(scheme, rest string) (Reference, error) {
+	rest, selector, hasSelector := strings.Cut(rest, "#")
+	path, rawQuery, _ := strings.Cut(rest, "?")
+	if path == "" {
+		return Reference{}, fmt.Errorf("empty path")
+	}
+	if _, err := url.ParseQuery(rawQuery); err != nil {
+		return Reference{}, fmt.Errorf("invalid query: %w", err)
+	}
+	if hasSelector {
+		if selector == "" {
+			return Reference{}, fmt.Errorf("empty selector")
+		}
+		if _, err := parseSelector(selector); err != nil {
+			return Reference{}, err
+		}
+	}
+
+	return Reference{Scheme: scheme, Path: path, RawQuery: rawQuery, Selector: selector}, nil
+}
+
+// WithStrictReferences makes the parser recognize only references in the
+// "secret+<scheme>://" form, so plain values like "aws:..." are never resolved
+func WithStrictReferences() Option {
+	return func(p *Parser) {
+		p.strictReferences = true
+	}
+}
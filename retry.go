@@ -0,0 +1,161 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultRetryInitialBackoff = 100 * time.Millisecond
+	defaultRetryMaxBackoff     = 5 * time.Second
+)
+
+// RetryPolicy configures the retries of failed provider calls
+type RetryPolicy struct {
+	// MaxAttempts is the number of calls including the first one, values below 2 disable retries
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, 100ms by default.
+	// It doubles with every retry up to MaxBackoff, 5s by default
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Jitter is the fraction of the delay chosen at random, from 0 to 1
+	Jitter float64
+	// Retryable reports whether a call failing with err should be retried, IsRetryable by default
+	Retryable func(err error) bool
+	// OnRetry is called before every retry
+	OnRetry func(attempt RetryAttempt)
+}
+
+// RetryAttempt describes a failed call about to be retried
+type RetryAttempt struct {
+	// Reference is the secret reference, or the comma separated references of a batch
+	Reference string
+	// Attempt is the number of the failed call, starting at 1
+	Attempt int
+	Err     error
+	// Delay is the time to wait before the retry
+	Delay time.Duration
+}
+
+// WithRetry makes the parser retry the provider calls failing with retryable errors
+func WithRetry(policy RetryPolicy) Option {
+	return func(p *Parser) {
+		p.retry = policy
+	}
+}
+
+// IsRetryable reports whether err is a transient error: an unavailable or exhausted GCP service,
+// an AWS throttling or server error, a 429 or 5xx response from Vault or Azure, or a network error.
+// Errors such as NotFound and AccessDenied are permanent
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		switch grpcErr.GRPCStatus().Code() {
+		case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+			return true
+		}
+		return false
+	}
+
+	var awsFailure awserr.RequestFailure
+	if errors.As(err, &awsFailure) && isRetryableStatus(awsFailure.StatusCode()) {
+		return true
+	}
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		return request.IsErrorThrottle(awsErr) || request.IsErrorRetryable(awsErr)
+	}
+
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return isRetryableStatus(statusErr.StatusCode)
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+var (
+	jitterMu   sync.Mutex                                        //nolint:gochecknoglobals
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano())) //nolint:gochecknoglobals, gosec
+)
+
+// backoff returns the delay before the retry following the failed attempt
+func (r *RetryPolicy) backoff(attempt int) time.Duration {
+	delay, limit := r.InitialBackoff, r.MaxBackoff
+	if delay <= 0 {
+		delay = defaultRetryInitialBackoff
+	}
+	if limit <= 0 {
+		limit = defaultRetryMaxBackoff
+	}
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+
+	if r.Jitter > 0 {
+		jitterMu.Lock()
+		delay -= time.Duration(r.Jitter * jitterRand.Float64() * float64(delay))
+		jitterMu.Unlock()
+	}
+
+	return delay
+}
+
+// callProvider calls fn under the per-call timeout, retrying it as the retry policy says.
+// ref describes the call to the OnRetry hook
+func callProvider[T any](ctx context.Context, p *Parser, ref string, fn func(ctx context.Context) (T, error)) (T, error) {
+	retryable := p.retry.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	for attempt := 1; ; attempt++ {
+		value, err := callContext(ctx, p.timeout, fn)
+		if err == nil || attempt >= p.retry.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+			if err != nil && attempt > 1 {
+				err = fmt.Errorf("after %d attempts: %w", attempt, err)
+			}
+			return value, err
+		}
+
+		delay := p.retry.backoff(attempt)
+		if p.retry.OnRetry != nil {
+			p.retry.OnRetry(RetryAttempt{Reference: ref, Attempt: attempt, Err: err, Delay: delay})
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			var zero T
+			return zero, ctx.Err()
+		}
+	}
+}
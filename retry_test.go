@@ -0,0 +1,199 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "gcp_unavailable",
+			err:  fmt.Errorf("err while get gcp secret %w", status.Error(codes.Unavailable, "unavailable")),
+			want: true,
+		},
+		{
+			name: "gcp_not_found",
+			err:  fmt.Errorf("err while get gcp secret %w", status.Error(codes.NotFound, "not found")),
+		},
+		{
+			name: "gcp_permission_denied",
+			err:  status.Error(codes.PermissionDenied, "denied"),
+		},
+		{
+			name: "aws_throttling",
+			err:  fmt.Errorf("err while get aws secret: %w", awserr.NewRequestFailure(awserr.New("ThrottlingException", "rate exceeded", nil), http.StatusBadRequest, "id")),
+			want: true,
+		},
+		{
+			name: "aws_server_error",
+			err:  awserr.NewRequestFailure(awserr.New("InternalServerError", "oops", nil), http.StatusInternalServerError, "id"),
+			want: true,
+		},
+		{
+			name: "aws_not_found",
+			err:  awserr.NewRequestFailure(awserr.New("ParameterNotFound", "missing", nil), http.StatusBadRequest, "id"),
+		},
+		{
+			name: "aws_access_denied",
+			err:  awserr.NewRequestFailure(awserr.New("AccessDeniedException", "denied", nil), http.StatusBadRequest, "id"),
+		},
+		{
+			name: "http_too_many_requests",
+			err:  fmt.Errorf("err while get vault secret: %w", &statusError{StatusCode: http.StatusTooManyRequests}),
+			want: true,
+		},
+		{
+			name: "http_unavailable",
+			err:  &statusError{StatusCode: http.StatusServiceUnavailable},
+			want: true,
+		},
+		{
+			name: "http_forbidden",
+			err:  &statusError{StatusCode: http.StatusForbidden},
+		},
+		{
+			name: "network",
+			err:  fmt.Errorf("vault request failed: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}),
+			want: true,
+		},
+		{
+			name: "call_timeout",
+			err:  context.DeadlineExceeded,
+			want: true,
+		},
+		{
+			name: "canceled",
+			err:  context.Canceled,
+		},
+		{
+			name: "unknown",
+			err:  errors.New("invalid reference"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, IsRetryable(tt.err))
+		})
+	}
+}
+
+func TestParser_ParseRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		maxAttempts  int
+		wantCalls    int
+		wantAttempts []int
+		wantErr      string
+	}{
+		{
+			name:         "recovers",
+			failures:     2,
+			err:          status.Error(codes.Unavailable, "unavailable"),
+			maxAttempts:  3,
+			wantCalls:    3,
+			wantAttempts: []int{1, 2},
+		},
+		{
+			name:         "exhausted",
+			failures:     5,
+			err:          status.Error(codes.Unavailable, "unavailable"),
+			maxAttempts:  3,
+			wantCalls:    3,
+			wantAttempts: []int{1, 2},
+			wantErr:      "after 3 attempts",
+		},
+		{
+			name:        "permanent",
+			failures:    5,
+			err:         status.Error(codes.NotFound, "not found"),
+			maxAttempts: 3,
+			wantCalls:   1,
+			wantErr:     "not found",
+		},
+		{
+			name:      "disabled",
+			failures:  1,
+			err:       status.Error(codes.Unavailable, "unavailable"),
+			wantCalls: 1,
+			wantErr:   "unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			var attempts []int
+			p := NewParser(nil, nil, WithSources(mapSource{name: "test", values: map[string]string{
+				"TOKEN": "flaky:token",
+			}}), WithRetry(RetryPolicy{
+				MaxAttempts:    tt.maxAttempts,
+				InitialBackoff: time.Millisecond,
+				Jitter:         0.5,
+				OnRetry: func(attempt RetryAttempt) {
+					if attempt.Reference == "secret+flaky://token" && errors.Is(attempt.Err, tt.err) {
+						attempts = append(attempts, attempt.Attempt)
+					}
+				},
+			}))
+			p.RegisterProvider("flaky", SecretProviderFunc(func(ref string) (string, error) {
+				calls++
+				if calls <= tt.failures {
+					return "", tt.err
+				}
+				return "value", nil
+			}))
+
+			got := &struct {
+				Token string `config:"TOKEN"`
+			}{}
+			err := p.Parse(got)
+			require.Equal(t, tt.wantCalls, calls)
+			require.Equal(t, tt.wantAttempts, attempts)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "value", got.Token)
+		})
+	}
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	var got []time.Duration
+	for attempt := 1; attempt <= 6; attempt++ {
+		got = append(got, policy.backoff(attempt))
+	}
+	require.Equal(t, []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}, got)
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.backoff(2)
+		require.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		require.LessOrEqual(t, delay, 200*time.Millisecond)
+	}
+}
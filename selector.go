@@ -0,0 +1,91 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// selectValue extracts the value at selector from a JSON or YAML payload.
+// The selector is a dotted path like "db.password" or a JSONPath subset like
+// "$.db.password", "$.hosts[0]" or "$['user name']". Strings are returned as is,
+// any other value is encoded as JSON
+func selectValue(payload, selector string) (string, error) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(payload), &doc); err != nil {
+		if yamlErr := yaml.Unmarshal([]byte(payload), &doc); yamlErr != nil {
+			return "", fmt.Errorf("secret payload is neither JSON nor YAML: %v", yamlErr)
+		}
+	}
+
+	steps, err := parseSelector(selector)
+	if err != nil {
+		return "", err
+	}
+	for _, step := range steps {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[step]
+			if !ok {
+				return "", fmt.Errorf("selector %q: key %q not found", selector, step)
+			}
+			doc = value
+		case []interface{}:
+			i, err := strconv.Atoi(step)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", fmt.Errorf("selector %q: index %q out of range", selector, step)
+			}
+			doc = node[i]
+		default:
+			return "", fmt.Errorf("selector %q: %q is not an object or a list", selector, step)
+		}
+	}
+
+	return stringify(doc)
+}
+
+// parseSelector splits a selector into object keys and list indexes
+func parseSelector(selector string) ([]string, error) {
+	rest := selector
+	if strings.HasPrefix(rest, "$") {
+		rest = rest[1:]
+	} else {
+		rest = "." + rest
+	}
+
+	var steps []string
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid selector %q: empty key", selector)
+			}
+			steps = append(steps, rest[1:end+1])
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid selector %q: missing ']'", selector)
+			}
+			step := rest[1:end]
+			if len(step) >= 2 && (step[0] == '\'' || step[0] == '"') && step[len(step)-1] == step[0] {
+				step = step[1 : len(step)-1]
+			} else if _, err := strconv.Atoi(step); err != nil {
+				return nil, fmt.Errorf("invalid selector %q: index %q is not a number", selector, step)
+			}
+			steps = append(steps, step)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid selector %q", selector)
+		}
+	}
+
+	return steps, nil
+}
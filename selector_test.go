@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectValue(t *testing.T) {
+	jsonPayload := `{"user":"app","password":"secret","port":5432,"db":{"hosts":["h1","h2"],"user name":"x"},"tls":true}`
+	yamlPayload := "user: app\ndb:\n  hosts:\n    - h1\n    - h2\n  port: 5432\n"
+	tests := []struct {
+		name     string
+		payload  string
+		selector string
+		want     string
+		wantErr  bool
+	}{
+		{name: "key", payload: jsonPayload, selector: "password", want: "secret"},
+		{name: "number", payload: jsonPayload, selector: "port", want: "5432"},
+		{name: "bool", payload: jsonPayload, selector: "tls", want: "true"},
+		{name: "dotted", payload: jsonPayload, selector: "db.hosts", want: `["h1","h2"]`},
+		{name: "jsonpath", payload: jsonPayload, selector: "$.db.hosts[1]", want: "h2"},
+		{name: "jsonpath_quoted_key", payload: jsonPayload, selector: "$.db['user name']", want: "x"},
+		{name: "jsonpath_double_quoted_key", payload: jsonPayload, selector: `$["user"]`, want: "app"},
+		{name: "yaml", payload: yamlPayload, selector: "$.db.hosts[0]", want: "h1"},
+		{name: "yaml_number", payload: yamlPayload, selector: "db.port", want: "5432"},
+		{name: "missing_key", payload: jsonPayload, selector: "host", wantErr: true},
+		{name: "index_out_of_range", payload: jsonPayload, selector: "$.db.hosts[2]", wantErr: true},
+		{name: "not_an_object", payload: jsonPayload, selector: "user.name", wantErr: true},
+		{name: "invalid_index", payload: jsonPayload, selector: "$.db.hosts[x]", wantErr: true},
+		{name: "unterminated", payload: jsonPayload, selector: "$.db.hosts[0", wantErr: true},
+		{name: "empty_key", payload: jsonPayload, selector: "db..hosts", wantErr: true},
+		{name: "plain_payload", payload: "plain: [", selector: "user", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectValue(tt.payload, tt.selector)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParser_ParseSelector(t *testing.T) {
+	calls := map[string]int{}
+	p := NewParser(nil, nil)
+	p.RegisterProvider("custom", SecretProviderFunc(func(ref string) (string, error) {
+		calls[ref]++
+		return `{"user":"app","password":"secret","hosts":["h1:5432","h2:5432"]}`, nil
+	}))
+	require.NoError(t, os.Setenv("SELECTOR_USER", "custom:db-creds#user"))
+	require.NoError(t, os.Setenv("SELECTOR_PASSWORD", "secret+custom://db-creds#password"))
+	require.NoError(t, os.Setenv("SELECTOR_HOST", "custom:db-creds#$.hosts[1]"))
+	require.NoError(t, os.Setenv("SELECTOR_MISSING", "custom:db-creds#port"))
+
+	got := &struct {
+		User     string `config:"SELECTOR_USER"`
+		Password string `config:"SELECTOR_PASSWORD"`
+		Host     string `config:"SELECTOR_HOST"`
+	}{}
+	require.NoError(t, p.Parse(got))
+	require.Equal(t, "app", got.User)
+	require.Equal(t, "secret", got.Password)
+	require.Equal(t, "h2:5432", got.Host)
+	require.Equal(t, map[string]int{"db-creds": 1}, calls)
+
+	require.NoError(t, p.Parse(got))
+	require.Equal(t, map[string]int{"db-creds": 2}, calls)
+
+	require.Error(t, p.Parse(&struct {
+		Port string `config:"SELECTOR_MISSING"`
+	}{}))
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReloadOnSignal makes the watcher reload when the process receives one of signals, SIGHUP by default,
+// like nginx does on "kill -HUP". The fresh copy is swapped in only when it parses, and done, when set,
+// is called after every such reload with its error, nil on success. The reloads bypass the secret cache
+func (w *Watcher) ReloadOnSignal(done func(err error), signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer signal.Stop(received)
+
+		for {
+			select {
+			case <-w.ctx.Done():
+				return
+			case <-received:
+				err := w.Reload(BypassCache(w.ctx))
+				if done != nil {
+					done(err)
+				}
+			}
+		}
+	}()
+}
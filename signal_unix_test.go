@@ -0,0 +1,41 @@
+//go:build unix
+
+package config
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher_ReloadOnSignal(t *testing.T) {
+	source := &syncSource{values: map[string]string{"NAME": "app"}}
+	p := NewParser(nil, nil, WithSources(source))
+	w, err := p.Watch(context.Background(), &watchConfig{}, 0)
+	require.NoError(t, err)
+	defer w.Stop()
+
+	reloaded := make(chan error, 1)
+	w.ReloadOnSignal(func(err error) { reloaded <- err }, syscall.SIGUSR1)
+	signal := func() error {
+		require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+		select {
+		case err := <-reloaded:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reload")
+			return nil
+		}
+	}
+
+	source.set("NAME", "renamed")
+	require.NoError(t, signal())
+	require.Equal(t, "renamed", w.Current().(*watchConfig).Name)
+
+	source.set("DB.TIMEOUT", "never")
+	require.Error(t, signal())
+	require.Equal(t, "renamed", w.Current().(*watchConfig).Name)
+}
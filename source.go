@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"sort"
+)
+
+// Source looks up raw configuration values by the key from the config tag
+type Source interface {
+	Lookup(key string) (string, bool)
+	// Name identifies the source in the field origins
+	Name() string
+}
+
+// loader is implemented by sources that read their values ahead of lookups.
+// Load is called at the start of every Parse
+type loader interface {
+	Load() error
+}
+
+// EnvSource looks up values in the process environment
+type EnvSource struct{}
+
+// Lookup returns the value of the environment variable named by key
+func (EnvSource) Lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+// Name returns "env"
+func (EnvSource) Name() string {
+	return "env"
+}
+
+// WithSources sets the ordered list of sources. A key is taken from the first source that has it,
+// flags set on the command line always come first. The environment is only consulted when
+// EnvSource is in the list. Sources added by later options are appended to the list
+func WithSources(sources ...Source) Option {
+	return func(p *Parser) {
+		p.sources = append([]Source{}, sources...)
+	}
+}
+
+// Origin describes where the value of a field came from
+type Origin struct {
+	// Field is the path of the struct field, e.g. "DB.Timeout"
+	Field string
+	// Key is the key the value was found under, e.g. "db.timeout"
+	Key string
+	// Source is the name of the source that had the key
+	Source string
+	// Reference is the secret reference the value was resolved from, empty for plain values
+	Reference string
+}
+
+// Origins returns the origins of the fields set by the last Parse, sorted by field
+func (p *Parser) Origins() []Origin {
+	stored, _ := p.origins.Load().(map[string]Origin)
+	origins := make([]Origin, 0, len(stored))
+	for _, origin := range stored {
+		origins = append(origins, origin)
+	}
+	sort.Slice(origins, func(i, j int) bool {
+		return origins[i].Field < origins[j].Field
+	})
+
+	return origins
+}
+
+// orderedSources returns the sources in precedence order
+func (p *Parser) orderedSources() []Source {
+	sources := p.sources
+	if sources == nil {
+		sources = []Source{EnvSource{}}
+	}
+
+	return append([]Source{flagSource{flags: p.flags}}, sources...)
+}
+
+// lookup returns the value for key from the first source that has it, with the key
+// and the name of the source it was found in. Inside nested struct fields the dotted
+// key "prefix.key" is tried in every source before key, which flags never match
+func (p *Parser) lookup(prefix, key string) (value, foundKey, source string, ok bool) {
+	return lookupSources(p.orderedSources(), prefix, key)
+}
+
+// lookupRun is lookup limited to the source of the path reference the run is scoped to, if any
+func (p *Parser) lookupRun(run *parseRun, prefix, key string) (value, foundKey, source string, ok bool) {
+	if run.scope != nil {
+		return lookupSources([]Source{run.scope}, prefix, key)
+	}
+
+	return p.lookup(prefix, key)
+}
+
+func lookupSources(sources []Source, prefix, key string) (value, foundKey, source string, ok bool) {
+	if key == "" {
+		return "", "", "", false
+	}
+	keys := []string{key}
+	if prefix != "" {
+		keys = []string{joinKey(prefix, key), key}
+	}
+
+	for i, k := range keys {
+		for _, s := range sources {
+			// flags are generated for the dotted key of every field, the bare key is the flag of another field
+			if _, isFlags := s.(flagSource); isFlags && i > 0 {
+				continue
+			}
+			if value, ok = s.Lookup(k); ok {
+				return value, k, s.Name(), true
+			}
+		}
+	}
+
+	return "", "", "", false
+}
+
+// loadSources reloads the sources that read their values ahead of lookups
+func (p *Parser) loadSources() error {
+	for _, source := range p.sources {
+		if l, ok := source.(loader); ok {
+			if err := l.Load(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
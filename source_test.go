@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mapSource is a Source backed by a map
+type mapSource struct {
+	name   string
+	values map[string]string
+}
+
+func (m mapSource) Lookup(key string) (string, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+func (m mapSource) Name() string {
+	return m.name
+}
+
+func TestParser_ParseSources(t *testing.T) {
+	type cfg struct {
+		Host    string        `config:"SOURCE_HOST"`
+		Timeout time.Duration `config:"SOURCE_TIMEOUT"`
+		DB      struct {
+			User string `config:"user"`
+		} `config:"db"`
+	}
+	require.NoError(t, os.Setenv("SOURCE_HOST", "env"))
+	defer os.Unsetenv("SOURCE_HOST")
+	first := mapSource{name: "first", values: map[string]string{"SOURCE_TIMEOUT": "1s", "user": "bare"}}
+	second := mapSource{name: "second", values: map[string]string{"SOURCE_HOST": "second", "SOURCE_TIMEOUT": "2s", "db.user": "nested"}}
+
+	tests := []struct {
+		name        string
+		sources     []Source
+		want        cfg
+		wantOrigins []Origin
+	}{
+		{
+			name:    "first_source_wins",
+			sources: []Source{first, EnvSource{}, second},
+			want: cfg{Host: "env", Timeout: time.Second, DB: struct {
+				User string `config:"user"`
+			}{User: "nested"}},
+			wantOrigins: []Origin{
+				{Field: "DB.User", Key: "db.user", Source: "second"},
+				{Field: "Host", Key: "SOURCE_HOST", Source: "env"},
+				{Field: "Timeout", Key: "SOURCE_TIMEOUT", Source: "first"},
+			},
+		},
+		{
+			name:    "bare_key_fallback",
+			sources: []Source{first},
+			want: cfg{Timeout: time.Second, DB: struct {
+				User string `config:"user"`
+			}{User: "bare"}},
+			wantOrigins: []Origin{
+				{Field: "DB.User", Key: "user", Source: "first"},
+				{Field: "Timeout", Key: "SOURCE_TIMEOUT", Source: "first"},
+			},
+		},
+		{
+			name:    "environment_not_listed",
+			sources: []Source{second, first},
+			want: cfg{Host: "second", Timeout: 2 * time.Second, DB: struct {
+				User string `config:"user"`
+			}{User: "nested"}},
+			wantOrigins: []Origin{
+				{Field: "DB.User", Key: "db.user", Source: "second"},
+				{Field: "Host", Key: "SOURCE_HOST", Source: "second"},
+				{Field: "Timeout", Key: "SOURCE_TIMEOUT", Source: "second"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser(nil, nil, WithSources(tt.sources...))
+			got := cfg{}
+			require.NoError(t, p.Parse(&got))
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantOrigins, p.Origins())
+		})
+	}
+}
+
+func TestParser_Origins(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(secret, []byte("password"), 0o600))
+	dotenv := filepath.Join(dir, ".env")
+	require.NoError(t, os.WriteFile(dotenv, []byte("ORIGIN_PASSWORD=secret+file://"+secret+"\nORIGIN_NAME=name\n"), 0o600))
+
+	p := NewParser(nil, nil, WithDotenv(dotenv))
+	require.Empty(t, p.Origins())
+
+	got := &struct {
+		Name     string `config:"ORIGIN_NAME"`
+		Password string `config:"ORIGIN_PASSWORD"`
+		Missing  string `config:"ORIGIN_MISSING"`
+	}{}
+	require.NoError(t, p.Parse(got))
+	require.Equal(t, "password", got.Password)
+	require.Equal(t, []Origin{
+		{Field: "Name", Key: "ORIGIN_NAME", Source: "dotenv:" + dotenv},
+		{Field: "Password", Key: "ORIGIN_PASSWORD", Source: "dotenv:" + dotenv, Reference: "secret+file://" + secret},
+	}, p.Origins())
+}
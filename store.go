@@ -0,0 +1,98 @@
+package config
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Store holds the current copy of a config struct of type T. Load never blocks and always
+// returns a complete struct, so request handlers can read it while the config reloads.
+// The returned struct must not be modified
+type Store[T any] struct {
+	current atomic.Pointer[T]
+
+	mu          sync.Mutex
+	subscribers map[chan *T]struct{}
+}
+
+// NewStore returns a store holding v
+func NewStore[T any](v *T) *Store[T] {
+	s := &Store[T]{}
+	s.current.Store(v)
+
+	return s
+}
+
+// WatchStore parses a T with p and watches it like Parser.Watch. The returned store follows the reloads of the returned watcher
+func WatchStore[T any](ctx context.Context, p *Parser, interval time.Duration) (*Store[T], *Watcher, error) {
+	v := new(T)
+	w, err := p.Watch(ctx, v, interval)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s := NewStore(v)
+	w.OnChange(func(cfg interface{}, _ []Change) {
+		s.Set(cfg.(*T))
+	})
+	// a reload may have finished before the callback was registered
+	s.mu.Lock()
+	if current := w.Current().(*T); current != s.current.Load() {
+		s.publish(current)
+	}
+	s.mu.Unlock()
+
+	return s, w, nil
+}
+
+// Load returns the current copy
+func (s *Store[T]) Load() *T {
+	return s.current.Load()
+}
+
+// Set replaces the current copy and sends it to the subscribers
+func (s *Store[T]) Set(v *T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.publish(v)
+}
+
+// Subscribe returns a channel receiving every new copy and a function closing it.
+// A subscriber that falls behind only gets the latest copy
+func (s *Store[T]) Subscribe() (<-chan *T, func()) {
+	ch := make(chan *T, 1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscribers == nil {
+		s.subscribers = map[chan *T]struct{}{}
+	}
+	s.subscribers[ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			delete(s.subscribers, ch)
+			close(ch)
+		})
+	}
+}
+
+// publish stores v and sends it to the subscribers, replacing a copy they have not received yet.
+// It must be called with s.mu held
+func (s *Store[T]) publish(v *T) {
+	s.current.Store(v)
+	for ch := range s.subscribers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- v
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchStore(t *testing.T) {
+	source := &syncSource{values: map[string]string{"NAME": "app", "DB.TIMEOUT": "1s"}}
+	p := NewParser(nil, nil, WithSources(source))
+
+	store, w, err := WatchStore[watchConfig](context.Background(), &p, 0)
+	require.NoError(t, err)
+	defer w.Stop()
+	require.Equal(t, "app", store.Load().Name)
+
+	updates, unsubscribe := store.Subscribe()
+
+	// readers never see a struct from two reloads
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				cfg := store.Load()
+				want := fmt.Sprintf("app%d", int(cfg.DB.Timeout.Seconds()))
+				if want == "app1" {
+					want = "app"
+				}
+				if cfg.Name != want {
+					t.Errorf("inconsistent config %+v", cfg)
+				}
+			}
+		}()
+	}
+	for i := 2; i <= 5; i++ {
+		source.mu.Lock()
+		source.values["NAME"] = fmt.Sprintf("app%d", i)
+		source.values["DB.TIMEOUT"] = fmt.Sprintf("%ds", i)
+		source.mu.Unlock()
+		require.NoError(t, w.Reload(context.Background()))
+	}
+	close(stop)
+	wg.Wait()
+
+	require.Equal(t, "app5", store.Load().Name)
+	select {
+	case cfg := <-updates:
+		require.Same(t, store.Load(), cfg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no update")
+	}
+
+	unsubscribe()
+	unsubscribe()
+	_, ok := <-updates
+	require.False(t, ok)
+
+	source.set("DB.TIMEOUT", "broken")
+	require.Error(t, w.Reload(context.Background()))
+	require.Equal(t, "app5", store.Load().Name)
+}
+
+func TestStore_Subscribe(t *testing.T) {
+	store := NewStore(&watchConfig{Name: "first"})
+	updates, unsubscribe := store.Subscribe()
+	defer unsubscribe()
+
+	store.Set(&watchConfig{Name: "second"})
+	store.Set(&watchConfig{Name: "third"})
+	require.Equal(t, "third", (<-updates).Name)
+	require.Equal(t, "third", store.Load().Name)
+	require.Empty(t, updates)
+}
@@ -0,0 +1,328 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultVaultMount          = "secret"
+	defaultVaultAppRoleMount   = "approle"
+	defaultVaultKubernetesPath = "kubernetes"
+	defaultVaultKubernetesJWT  = "/var/run/secrets/kubernetes.io/serviceaccount/token" //nolint:gosec
+)
+
+// VaultAuth obtains a token used to talk to Vault
+type VaultAuth interface {
+	Login(ctx context.Context, c *VaultClient) (string, error)
+}
+
+// VaultTokenAuth authenticates with a static token
+type VaultTokenAuth struct {
+	Token string
+}
+
+// Login returns the static token
+func (a VaultTokenAuth) Login(context.Context, *VaultClient) (string, error) {
+	if a.Token == "" {
+		return "", fmt.Errorf("vault token is not set")
+	}
+	return a.Token, nil
+}
+
+// VaultAppRoleAuth authenticates with the AppRole auth method
+type VaultAppRoleAuth struct {
+	RoleID   string
+	SecretID string
+	// MountPath of the auth method, "approle" by default
+	MountPath string
+}
+
+// Login logs in with the role and secret IDs
+func (a VaultAppRoleAuth) Login(ctx context.Context, c *VaultClient) (string, error) {
+	mount := a.MountPath
+	if mount == "" {
+		mount = defaultVaultAppRoleMount
+	}
+	return c.login(ctx, mount, map[string]string{"role_id": a.RoleID, "secret_id": a.SecretID})
+}
+
+// VaultKubernetesAuth authenticates with the Kubernetes auth method using the pod service account token
+type VaultKubernetesAuth struct {
+	Role string
+	// TokenPath of the service account token, the default in-pod location is used when empty
+	TokenPath string
+	// MountPath of the auth method, "kubernetes" by default
+	MountPath string
+}
+
+// Login logs in with the service account token
+func (a VaultKubernetesAuth) Login(ctx context.Context, c *VaultClient) (string, error) {
+	tokenPath := a.TokenPath
+	if tokenPath == "" {
+		tokenPath = defaultVaultKubernetesJWT
+	}
+	mount := a.MountPath
+	if mount == "" {
+		mount = defaultVaultKubernetesPath
+	}
+
+	jwt, err := os.ReadFile(tokenPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read kubernetes service account token: %w", err)
+	}
+	return c.login(ctx, mount, map[string]string{"role": a.Role, "jwt": strings.TrimSpace(string(jwt))})
+}
+
+// VaultClient talks to the Vault HTTP API
+type VaultClient struct {
+	// Address of the Vault server, e.g. "https://vault:8200"
+	Address string
+	// Namespace sent with every request, if set
+	Namespace string
+	// Mount of the KV v2 secrets engine, "secret" by default
+	Mount      string
+	Auth       VaultAuth
+	HTTPClient *http.Client
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewVaultClient creates a new VaultClient
+func NewVaultClient(address string, auth VaultAuth) *VaultClient {
+	return &VaultClient{
+		Address:    address,
+		Mount:      defaultVaultMount,
+		Auth:       auth,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// ReadKV reads the data of the KV v2 secret at path. Version 0 means the latest version
+func (c *VaultClient) ReadKV(path string, version int) (map[string]interface{}, error) {
+	return c.ReadKVContext(context.Background(), path, version)
+}
+
+// ReadKVContext is ReadKV with a context for the request
+func (c *VaultClient) ReadKVContext(ctx context.Context, path string, version int) (map[string]interface{}, error) {
+	mount := c.Mount
+	if mount == "" {
+		mount = defaultVaultMount
+	}
+	endpoint := "/v1/" + strings.Trim(mount, "/") + "/data/" + strings.Trim(path, "/")
+	if version > 0 {
+		endpoint += "?version=" + strconv.Itoa(version)
+	}
+
+	var resp struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	err := c.authorized(ctx, func(token string) error {
+		return c.do(ctx, http.MethodGet, endpoint, token, nil, &resp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data.Data == nil {
+		return nil, fmt.Errorf("vault secret %q has no data, the version may be deleted", path)
+	}
+
+	return resp.Data.Data, nil
+}
+
+// authorized calls fn with a token, logging in again once if the token is rejected
+func (c *VaultClient) authorized(ctx context.Context, fn func(token string) error) error {
+	token, err := c.getToken(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	err = fn(token)
+	if statusErr, ok := err.(*statusError); ok && statusErr.StatusCode == http.StatusForbidden {
+		if token, err = c.getToken(ctx, true); err != nil {
+			return err
+		}
+		return fn(token)
+	}
+
+	return err
+}
+
+func (c *VaultClient) getToken(ctx context.Context, renew bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && !renew {
+		return c.token, nil
+	}
+	if c.Auth == nil {
+		return "", fmt.Errorf("vault auth is not set")
+	}
+
+	token, err := c.Auth.Login(ctx, c)
+	if err != nil {
+		return "", fmt.Errorf("vault login failed: %w", err)
+	}
+	c.token = token
+
+	return token, nil
+}
+
+func (c *VaultClient) login(ctx context.Context, mount string, body map[string]string) (string, error) {
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/auth/"+strings.Trim(mount, "/")+"/login", "", body, &resp); err != nil {
+		return "", err
+	}
+	if resp.Auth.ClientToken == "" {
+		return "", fmt.Errorf("vault login response has no client token")
+	}
+
+	return resp.Auth.ClientToken, nil
+}
+
+func (c *VaultClient) do(ctx context.Context, method, endpoint, token string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.Address, "/")+endpoint, reqBody)
+	if err != nil {
+		return fmt.Errorf("unable to create vault request: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if c.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.Namespace)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return decodeResponse(resp, out)
+}
+
+// VaultProvider resolves references against the Vault KV v2 secrets engine.
+// A reference is the secret path, optionally followed by a query selecting a field and a version,
+// e.g. "myapp/db?field=password&version=2". Without a field a secret with a single key resolves
+// to its value and any other secret to its data encoded as JSON
+type VaultProvider struct {
+	Client *VaultClient
+}
+
+// Resolve reads the secret at the given path from Vault
+func (v *VaultProvider) Resolve(key string) (string, error) {
+	return v.ResolveContext(context.Background(), key)
+}
+
+// ResolveContext is Resolve with a context for the request
+func (v *VaultProvider) ResolveContext(ctx context.Context, key string) (string, error) {
+	if v.Client == nil {
+		return key, fmt.Errorf("vault connection is not set")
+	}
+
+	path, rawQuery, _ := strings.Cut(key, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", fmt.Errorf("invalid vault selector %q: %w", rawQuery, err)
+	}
+
+	var version int
+	for k := range query {
+		switch k {
+		case "field":
+		case "version":
+			if version, err = strconv.Atoi(query.Get(k)); err != nil {
+				return "", fmt.Errorf("invalid vault secret version %q: %w", query.Get(k), err)
+			}
+		default:
+			return "", fmt.Errorf("vault selector %q not supported", k)
+		}
+	}
+
+	data, err := v.Client.ReadKVContext(ctx, path, version)
+	if err != nil {
+		return "", fmt.Errorf("err while get vault secret: %w", err)
+	}
+
+	if field := query.Get("field"); field != "" {
+		value, ok := data[field]
+		if !ok {
+			return "", fmt.Errorf("vault secret %q has no field %q", path, field)
+		}
+		return stringify(value)
+	}
+	if len(data) == 1 {
+		for _, value := range data {
+			return stringify(value)
+		}
+	}
+
+	return stringify(data)
+}
+
+// stringify returns strings as is and encodes any other value as JSON
+func stringify(value interface{}) (string, error) {
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode value: %w", err)
+	}
+	return string(data), nil
+}
+
+// statusError is returned by HTTP based providers for unexpected response statuses
+type statusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected response status %d: %s", e.StatusCode, e.Body)
+}
+
+// decodeResponse decodes a JSON response into out, returning a statusError for non 2xx statuses
+func decodeResponse(resp *http.Response, out interface{}) error {
+	const maxErrorBody = 1024
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return &statusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("unable to decode response: %w", err)
+	}
+
+	return nil
+}
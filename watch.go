@@ -0,0 +1,204 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Change is a field whose value changed on reload
+type Change struct {
+	// Field is the path of the struct field, e.g. "DB.Timeout"
+	Field string
+	Old   interface{}
+	New   interface{}
+}
+
+// Watcher parses a config struct again periodically and reports the changes
+type Watcher struct {
+	parser   *Parser
+	funcMap  map[reflect.Type]ParserFunc
+	parsers  map[reflect.Type]ParserFunc
+	interval time.Duration
+
+	reloadMu sync.Mutex
+	mu       sync.RWMutex
+	current  reflect.Value
+	onChange []func(cfg interface{}, changes []Change)
+	onError  []func(err error)
+	// targets are the files the current copy was read from, notifier watches them when set
+	targets  []watchTarget
+	notifier *fileNotifier
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Watch parses v, a pointer to a struct, and then parses a fresh copy of it every interval
+// until ctx is done or Stop is called. A zero interval disables polling, so the struct is only
+// parsed again by Reload or, with WatchFiles, when its files change. v itself is never changed after Watch returns,
+// the latest copy is returned by Current and passed to the OnChange callbacks.
+// When a parse fails the previous copy is kept and the error is passed to the OnError callbacks
+func (p *Parser) Watch(ctx context.Context, v interface{}, interval time.Duration) (*Watcher, error) {
+	return p.WatchWithFuncs(ctx, v, interval, map[reflect.Type]ParserFunc{})
+}
+
+// WatchWithFuncs is Watch with ParserFunc
+func (p *Parser) WatchWithFuncs(ctx context.Context, v interface{}, interval time.Duration, funcMap map[reflect.Type]ParserFunc) (*Watcher, error) {
+	if interval < 0 {
+		return nil, fmt.Errorf("watch interval must not be negative")
+	}
+	run, err := p.parse(ctx, v, funcMap)
+	if err != nil {
+		return nil, err
+	}
+
+	parsers := defaultTypeParsers()
+	for k, v := range funcMap {
+		parsers[k] = v
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	w := &Watcher{
+		parser:   p,
+		funcMap:  funcMap,
+		parsers:  parsers,
+		interval: interval,
+		current:  reflect.ValueOf(v),
+		targets:  p.watchTargets(run),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	if interval > 0 {
+		w.wg.Add(1)
+		go w.poll()
+	}
+
+	return w, nil
+}
+
+// OnChange registers fn to be called with the new copy of the struct and the changed fields after every reload that changed any
+func (w *Watcher) OnChange(fn func(cfg interface{}, changes []Change)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.onChange = append(w.onChange, fn)
+}
+
+// OnError registers fn to be called with the error of every failed reload
+func (w *Watcher) OnError(fn func(err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.onError = append(w.onError, fn)
+}
+
+// Current returns the latest successfully parsed copy of the struct
+func (w *Watcher) Current() interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.current.Interface()
+}
+
+// Stop stops the watcher and waits for a reload in progress to finish
+func (w *Watcher) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *Watcher) poll() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			_ = w.Reload(w.ctx)
+		}
+	}
+}
+
+// Reload parses a fresh copy of the struct now. On success the copy replaces the current one
+// and the OnChange callbacks are called if any field changed, otherwise the OnError callbacks are called.
+// Failing to watch the files read by the copy is reported to the OnError callbacks after it is published
+func (w *Watcher) Reload(ctx context.Context) error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	w.mu.RLock()
+	old := w.current
+	w.mu.RUnlock()
+
+	fresh := reflect.New(old.Type().Elem())
+	run, err := w.parser.parse(ctx, fresh.Interface(), w.funcMap)
+	if err != nil {
+		w.reportError(err)
+		return err
+	}
+
+	// the copy is published even when the files can not be watched, the error is reported after it
+	watchErr := w.setTargets(w.parser.watchTargets(run))
+	if watchErr != nil {
+		watchErr = fmt.Errorf("unable to watch the config files: %w", watchErr)
+	}
+
+	changes := diffFields(old.Elem(), fresh.Elem(), w.parsers, "", nil)
+	if len(changes) > 0 {
+		w.mu.Lock()
+		w.current = fresh
+		callbacks := w.onChange
+		w.mu.Unlock()
+		for _, fn := range callbacks {
+			fn(fresh.Interface(), changes)
+		}
+	}
+
+	if watchErr != nil {
+		w.reportError(watchErr)
+	}
+
+	return watchErr
+}
+
+// reportError calls the OnError callbacks with err
+func (w *Watcher) reportError(err error) {
+	w.mu.RLock()
+	callbacks := w.onError
+	w.mu.RUnlock()
+	for _, fn := range callbacks {
+		fn(err)
+	}
+}
+
+// diffFields appends the fields that differ between the structs old and fresh to changes, in field order.
+// Nested structs without a parser are compared field by field
+func diffFields(old, fresh reflect.Value, parsers map[reflect.Type]ParserFunc, path string, changes []Change) []Change {
+	refType := old.Type()
+	for i := 0; i < refType.NumField(); i++ {
+		sf := refType.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		fieldPath := joinKey(path, sf.Name)
+
+		if _, hasParser := parsers[sf.Type]; sf.Type.Kind() == reflect.Struct && !hasParser {
+			changes = diffFields(old.Field(i), fresh.Field(i), parsers, fieldPath, changes)
+			continue
+		}
+		if !reflect.DeepEqual(old.Field(i).Interface(), fresh.Field(i).Interface()) {
+			changes = append(changes, Change{
+				Field: fieldPath,
+				Old:   old.Field(i).Interface(),
+				New:   fresh.Field(i).Interface(),
+			})
+		}
+	}
+
+	return changes
+}
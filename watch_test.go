@@ -0,0 +1,113 @@
+package config
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// syncSource is a Source backed by a map that can be changed while a watcher reads it
+type syncSource struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (s *syncSource) Lookup(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	return value, ok
+}
+
+func (s *syncSource) Name() string {
+	return "sync"
+}
+
+func (s *syncSource) set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+}
+
+type watchConfig struct {
+	Name string `config:"NAME"`
+	DB   struct {
+		Postgres Postgres      `config:"URL"`
+		Timeout  time.Duration `config:"TIMEOUT"`
+	} `config:"DB"`
+}
+
+func TestParser_Watch(t *testing.T) {
+	source := &syncSource{values: map[string]string{
+		"NAME":       "app",
+		"DB.URL":     "postgres://app:old@db:5432/app",
+		"DB.TIMEOUT": "1s",
+	}}
+	p := NewParser(nil, nil, WithSources(source))
+
+	cfg := &watchConfig{}
+	w, err := p.Watch(context.Background(), cfg, time.Hour)
+	require.NoError(t, err)
+	defer w.Stop()
+	require.Equal(t, time.Second, cfg.DB.Timeout)
+
+	var changes []Change
+	var errs []error
+	w.OnChange(func(cfg interface{}, c []Change) {
+		changes = append(changes, c...)
+	})
+	w.OnError(func(err error) {
+		errs = append(errs, err)
+	})
+
+	require.NoError(t, w.Reload(context.Background()))
+	require.Empty(t, changes)
+
+	source.set("DB.TIMEOUT", "2s")
+	source.set("DB.URL", "postgres://app:new@db:5432/app")
+	require.NoError(t, w.Reload(context.Background()))
+	require.Len(t, changes, 2)
+	require.Equal(t, "DB.Postgres", changes[0].Field)
+	require.Equal(t, "old", changes[0].Old.(Postgres).Password)
+	require.Equal(t, "new", changes[0].New.(Postgres).Password)
+	require.Equal(t, Change{Field: "DB.Timeout", Old: time.Second, New: 2 * time.Second}, changes[1])
+
+	current := w.Current().(*watchConfig)
+	require.Equal(t, 2*time.Second, current.DB.Timeout)
+	require.Equal(t, time.Second, cfg.DB.Timeout)
+
+	source.set("DB.TIMEOUT", "soon")
+	require.Error(t, w.Reload(context.Background()))
+	require.Len(t, errs, 1)
+	require.Same(t, current, w.Current())
+	require.Len(t, changes, 2)
+}
+
+func TestParser_WatchInterval(t *testing.T) {
+	source := &syncSource{values: map[string]string{"NAME": "app"}}
+	p := NewParser(nil, nil, WithSources(source))
+
+	w, err := p.Watch(context.Background(), &watchConfig{}, 5*time.Millisecond)
+	require.NoError(t, err)
+	changed := make(chan []Change, 1)
+	w.OnChange(func(cfg interface{}, changes []Change) {
+		changed <- changes
+	})
+
+	source.set("NAME", "renamed")
+	select {
+	case changes := <-changed:
+		require.Equal(t, []Change{{Field: "Name", Old: "app", New: "renamed"}}, changes)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+	}
+	w.Stop()
+
+	_, err = p.Watch(context.Background(), &watchConfig{}, -time.Second)
+	require.Error(t, err)
+}